@@ -5,4 +5,5 @@ go 1.13
 require (
 	github.com/alecharmon/trie v1.0.1
 	github.com/labstack/gommon v0.3.0 // indirect
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,140 @@
+package codeowners
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Aliases maps owners to the owner they should be written as, such as an
+// email to a handle, an old handle to a new one or a person to their team.
+// It is loaded from a YAML or JSON file, e.g
+//
+//	aliases:
+//	  jane@example.com: "@jane"
+//	  "@jdoe-old": "@jane"
+//	  "@contractor": "@org/contractors"
+//
+// Mappings are followed until an owner without an alias is reached, owners
+// are matched regardless of case.
+type Aliases struct {
+	aliases map[string]string
+}
+
+// Substitution is an owner of an entry or section header replaced by
+// RewriteOwners
+type Substitution struct {
+	Entry   *Entry   // rule whose owner was replaced, nil for a section header
+	Section *Section // section whose default owner was replaced, nil for a rule
+	Old     string
+	New     string
+}
+
+type aliasesFile struct {
+	Aliases map[string]string `yaml:"aliases" json:"aliases"`
+}
+
+// LoadAliases reads aliases from a JSON or YAML file
+func LoadAliases(filePath string) (*Aliases, error) {
+	f := aliasesFile{}
+	if err := decodeConfigFile(filePath, &f); err != nil {
+		return nil, err
+	}
+	return NewAliases(f.Aliases)
+}
+
+// NewAliases creates aliases from a mapping of owners to their replacement.
+// It returns an error when following the mapping would never end.
+func NewAliases(mapping map[string]string) (*Aliases, error) {
+	a := &Aliases{aliases: map[string]string{}}
+	for from, to := range mapping {
+		a.aliases[strings.ToLower(from)] = to
+	}
+	for _, from := range a.sources() {
+		chain := []string{from}
+		for owner := a.aliases[from]; ; owner = a.aliases[strings.ToLower(owner)] {
+			for _, seen := range chain {
+				if strings.EqualFold(seen, owner) {
+					return nil, fmt.Errorf("aliases map owners to each other: %s", strings.Join(append(chain, owner), " -> "))
+				}
+			}
+			if _, ok := a.aliases[strings.ToLower(owner)]; !ok {
+				break
+			}
+			chain = append(chain, owner)
+		}
+	}
+	return a, nil
+}
+
+// Resolve returns the owner an owner should be written as, the owner itself
+// when it has no alias
+func (a *Aliases) Resolve(owner string) string {
+	for {
+		to, ok := a.aliases[strings.ToLower(owner)]
+		if !ok {
+			return owner
+		}
+		owner = to
+	}
+}
+
+// sources returns the aliased owners in a stable order
+func (a *Aliases) sources() []string {
+	sources := []string{}
+	for from := range a.aliases {
+		sources = append(sources, from)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// resolveAll resolves owners, dropping the duplicates aliases may create
+func (a *Aliases) resolveAll(owners []string) []string {
+	resolved := []string{}
+	for _, owner := range owners {
+		resolved = append(resolved, a.Resolve(owner))
+	}
+	return removeDuplicates(resolved)
+}
+
+// RewriteOwners replaces every aliased owner of the rules and section headers
+// with the owner it resolves to, so saving the index rewrites the lines
+// concerned. Rules are rewritten with ReplaceOwner. It returns every
+// substitution made, in file order.
+func (t *CodeOwners) RewriteOwners(a *Aliases) []Substitution {
+	substitutions := []Substitution{}
+	for _, l := range t.document().lines {
+		owners := []string{}
+		if l.entry != nil {
+			owners = l.entry.owners
+		} else if l.section != nil {
+			owners = l.section.DefaultOwners
+		}
+		for _, owner := range owners {
+			if resolved := a.Resolve(owner); resolved != owner {
+				substitutions = append(substitutions, Substitution{Entry: l.entry, Section: l.section, Old: owner, New: resolved})
+			}
+		}
+	}
+
+	for _, s := range substitutions {
+		if s.Section != nil {
+			for i, owner := range s.Section.DefaultOwners {
+				if owner == s.Old {
+					s.Section.DefaultOwners[i] = s.New
+				}
+			}
+			continue
+		}
+		t.ReplaceOwner(s.Old, s.New)
+	}
+	for _, s := range substitutions {
+		if s.Section != nil {
+			s.Section.DefaultOwners = removeDuplicates(s.Section.DefaultOwners)
+		} else {
+			s.Entry.owners = removeDuplicates(s.Entry.owners)
+		}
+	}
+	return substitutions
+}
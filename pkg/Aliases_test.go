@@ -0,0 +1,112 @@
+package codeowners
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const aliasedCodeOwners = `* @default
+*.js Jane@Example.com @jdoe-old @frank # web
+docs/ @contractor @docs
+`
+
+func TestWithAliases(t *testing.T) {
+	for _, file := range []string{"fixtures/aliases/aliases.yaml", "fixtures/aliases/aliases.json"} {
+		a, err := LoadAliases(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if owner := a.Resolve("@jdoe-old"); owner != "@org/web" {
+			t.Errorf("%s : expected the chain of aliases to be followed got %s", file, owner)
+		}
+
+		co, errs := BuildIndex([]byte(aliasedCodeOwners), WithAliases(a))
+		if len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		if owners := co.FindOwners("app.js"); !reflect.DeepEqual(owners, []string{"@org/web", "@frank"}) {
+			t.Errorf("%s : expected normalized owners got %v", file, owners)
+		}
+		if owners := co.FindOwners("docs/index.md"); !reflect.DeepEqual(owners, []string{"@org/contractors", "@docs"}) {
+			t.Errorf("%s : expected normalized owners got %v", file, owners)
+		}
+		if co.doc.String() != aliasedCodeOwners {
+			t.Errorf("%s : expected the file to be unchanged got %q", file, co.doc.String())
+		}
+	}
+}
+
+func TestRewriteOwners(t *testing.T) {
+	a, err := LoadAliases("fixtures/aliases/aliases.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	co, errs := BuildIndex([]byte(aliasedCodeOwners))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	substitutions := co.RewriteOwners(a)
+	expected := []struct {
+		line     int
+		old, new string
+	}{
+		{line: 2, old: "Jane@Example.com", new: "@org/web"},
+		{line: 2, old: "@jdoe-old", new: "@org/web"},
+		{line: 3, old: "@contractor", new: "@org/contractors"},
+	}
+	if len(substitutions) != len(expected) {
+		t.Fatalf("expected %d substitutions got %v", len(expected), substitutions)
+	}
+	for i, s := range substitutions {
+		if s.Entry.Line() != expected[i].line || s.Old != expected[i].old || s.New != expected[i].new {
+			t.Errorf("expected %+v got %+v", expected[i], s)
+		}
+	}
+
+	rewritten := `* @default
+*.js @org/web @frank # web
+docs/ @org/contractors @docs
+`
+	if co.doc.String() != rewritten {
+		t.Errorf("expected %q got %q", rewritten, co.doc.String())
+	}
+	if substitutions := co.RewriteOwners(a); len(substitutions) != 0 {
+		t.Errorf("expected nothing left to rewrite got %v", substitutions)
+	}
+}
+
+func TestRewriteOwnersWithAliases(t *testing.T) {
+	a, err := NewAliases(map[string]string{"@old": "@new", "@contractor": "@org/contractors"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := "[Docs] @old # docs team\ndocs/\n*.md @contractor\n"
+	co, errs := BuildIndex([]byte(input), WithAliases(a))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if owners := co.FindOwners("docs/index.html"); !reflect.DeepEqual(owners, []string{"@new"}) {
+		t.Errorf("expected the section default owners to be normalized got %v", owners)
+	}
+	if co.doc.String() != input {
+		t.Errorf("expected the file to be unchanged got %q", co.doc.String())
+	}
+
+	substitutions := co.RewriteOwners(a)
+	if len(substitutions) != 2 || substitutions[0].Section == nil || substitutions[1].Entry.Line() != 3 {
+		t.Fatalf("expected the section header and the rule to be rewritten got %+v", substitutions)
+	}
+	rewritten := "[Docs] @new # docs team\ndocs/\n*.md @org/contractors\n"
+	if co.doc.String() != rewritten {
+		t.Errorf("expected %q got %q", rewritten, co.doc.String())
+	}
+}
+
+func TestAliasesCycle(t *testing.T) {
+	_, err := NewAliases(map[string]string{"@a": "@b", "@b": "@c", "@c": "@A"})
+	if err == nil || !strings.Contains(err.Error(), "aliases map owners to each other: @a -> @b -> @c -> @A") {
+		t.Errorf("expected a cycle error got %v", err)
+	}
+}
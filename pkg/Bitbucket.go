@@ -0,0 +1,162 @@
+package codeowners
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GroupDefinition is a Bitbucket owner group declared in the CODEOWNERS file,
+// e.g
+//
+//	@@@Backend @jane @john
+//
+// Rules and merge checks refer to the group as @@Backend.
+type GroupDefinition struct {
+	Name    string   // name of the group without its @ signs
+	Members []string // users and emails belonging to the group
+	line    int
+}
+
+// Line returns the line number of the group definition
+func (g *GroupDefinition) Line() int {
+	return g.line
+}
+
+// Owner returns the group as it is written in rules, e.g @@Backend
+func (g *GroupDefinition) Owner() string {
+	return "@@" + g.Name
+}
+
+// MergeCheck is a Bitbucket merge check requiring a number of approvals from
+// the members of a group, e.g
+//
+//	Check(@@Backend >= 2)
+//
+// The check only applies to pull requests changing files the group owns.
+type MergeCheck struct {
+	Group     string // group the approvals must come from, e.g @@Backend
+	Approvals int    // minimum number of approvals
+	line      int
+}
+
+// Line returns the line number of the merge check
+func (c *MergeCheck) Line() int {
+	return c.line
+}
+
+// MergeCheckResult is the outcome of a MergeCheck for a pull request
+type MergeCheckResult struct {
+	Check     *MergeCheck
+	Required  bool     // the group owns at least one of the changed files
+	Approvers []string // approvers belonging to the group
+	Passed    bool
+}
+
+var mergeCheck = regexp.MustCompile(`^Check\(\s*(@@[^\s)]+)\s*>=\s*(\d+)\s*\)$`)
+
+// groupDialect is implemented by dialects which declare owner groups and
+// merge checks in the CODEOWNERS file
+type groupDialect interface {
+	ownerGroups() bool
+}
+
+func (bitbucketDialect) ownerGroups() bool { return true }
+
+// parseGroupDefinition returns the group declared by a line starting with @@@
+func (p *Parser) parseGroupDefinition(words []word) (*GroupDefinition, error) {
+	name := strings.TrimPrefix(words[0].lit, "@@@")
+	if !rxName.MatchString(name) {
+		return nil, newDiagnostic(CodeInvalidOwner, words[0].pos, words[0].lit, "(%s) is an invalid group name", words[0].lit)
+	}
+	g := &GroupDefinition{Name: name, Members: []string{}}
+	for _, w := range words[1:] {
+		kind, err := p.dialect.ClassifyOwner(w.lit)
+		if err != nil || kind == GroupOwner {
+			return nil, newDiagnostic(CodeInvalidOwner, w.pos, w.lit, "(%s) is an invalid group member", w.lit)
+		}
+		g.Members = append(g.Members, w.lit)
+	}
+	return g, nil
+}
+
+// parseMergeCheck returns the merge check declared by the content of a line,
+// nil when the line is not a merge check
+func (p *Parser) parseMergeCheck(content string, words []word) (*MergeCheck, error) {
+	if !strings.HasPrefix(content, "Check(") {
+		return nil, nil
+	}
+	m := mergeCheck.FindStringSubmatch(content)
+	if m == nil {
+		return nil, newDiagnostic(CodeInvalidMergeCheck, words[0].pos, content, "(%s) is an invalid merge check, expected Check(@@Group >= n)", content)
+	}
+	if p.groups[m[1][2:]] == nil {
+		return nil, newDiagnostic(CodeUndefinedGroup, words[0].pos, content, "%s is not defined", m[1])
+	}
+	approvals, _ := strconv.Atoi(m[2])
+	return &MergeCheck{Group: m[1], Approvals: approvals}, nil
+}
+
+// Groups returns the Bitbucket owner groups declared in the file
+func (t *CodeOwners) Groups() []*GroupDefinition {
+	return t.document().Groups()
+}
+
+// MergeChecks returns the Bitbucket merge checks declared in the file
+func (t *CodeOwners) MergeChecks() []*MergeCheck {
+	return t.document().MergeChecks()
+}
+
+// ExpandOwners replaces the Bitbucket groups among the owners with their
+// members, keeping the order of the owners and dropping duplicates
+func (t *CodeOwners) ExpandOwners(owners []string) []string {
+	groups := map[string]*GroupDefinition{}
+	for _, g := range t.Groups() {
+		groups[g.Owner()] = g
+	}
+	expanded := []string{}
+	for _, owner := range owners {
+		if g, ok := groups[owner]; ok {
+			expanded = append(expanded, g.Members...)
+			continue
+		}
+		expanded = append(expanded, owner)
+	}
+	return removeDuplicates(expanded)
+}
+
+// EvaluateMergeChecks evaluates the merge checks of the file for a pull
+// request changing the files and approved by the approvers. A check passes
+// when its group owns none of the changed files or enough of its members
+// approved.
+func (t *CodeOwners) EvaluateMergeChecks(changed []string, approvers []string) []MergeCheckResult {
+	approved := map[string]bool{}
+	for _, a := range approvers {
+		approved[a] = true
+	}
+	members := map[string][]string{}
+	for _, g := range t.Groups() {
+		members[g.Owner()] = g.Members
+	}
+
+	// Every changed file is looked up once, whatever the number of checks
+	owned := map[string]bool{}
+	for _, path := range changed {
+		for _, owner := range t.FindOwners(path) {
+			owned[owner] = true
+		}
+	}
+
+	results := []MergeCheckResult{}
+	for _, check := range t.MergeChecks() {
+		result := MergeCheckResult{Check: check, Approvers: []string{}, Required: owned[check.Group]}
+		for _, member := range members[check.Group] {
+			if approved[member] {
+				result.Approvers = append(result.Approvers, member)
+			}
+		}
+		result.Passed = !result.Required || len(result.Approvers) >= check.Approvals
+		results = append(results, result)
+	}
+	return results
+}
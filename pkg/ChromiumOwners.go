@@ -0,0 +1,295 @@
+package codeowners
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// OwnersFileName is the name of the per-directory ownership files used by
+// Chromium and Gerrit
+const OwnersFileName = "OWNERS"
+
+// Everyone is the owner listed as * in an OWNERS file, anyone may approve the
+// files it owns
+const Everyone = "*"
+
+// ownersFile is a parsed Chromium OWNERS file. Every line is one of
+//
+//	jane@example.com            owner of the directory
+//	*                           everyone owns the directory
+//	set noparent                owners of parent directories do not apply
+//	per-file *.gn=jane@example.com,john@example.com
+//	per-file *.gn=set noparent  only the per-file owners own the files
+//	file://path/to/OWNERS       owners of another OWNERS file, from the root
+//	file:OWNERS.docs            owners of another OWNERS file, from this directory
+//	include path/to/OWNERS      Gerrit spelling of file:
+type ownersFile struct {
+	owners   []string
+	noparent bool
+	perFile  []*perFileRule
+}
+
+// perFileRule holds the owners of the files of a directory matching a glob
+type perFileRule struct {
+	glob     string
+	owners   []string
+	noparent bool
+	line     int
+}
+
+// BuildFromOwnersTree walks the tree at root, reads every Chromium style OWNERS
+// file and builds an index giving the same owners as the OWNERS files would.
+// Every directory with an OWNERS file becomes a rule owned by its owners and,
+// unless it sets noparent, by the owners of its parent directories. Per-file
+// owners become rules for the files of their directory. The returned errors
+// name the OWNERS file and line at fault.
+func BuildFromOwnersTree(root string, opts ...Option) (*CodeOwners, []error) {
+	l := &ownersLoader{root: root, files: map[string]*ownersFile{}, dirs: map[string][]string{}}
+	errors := []error{}
+	entries := []*Entry{}
+	dirs := []string{}
+
+	walkErr := filepath.Walk(root, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Name() != OwnersFileName {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, filepath.Dir(filePath))
+		if err != nil {
+			return err
+		}
+		dirs = append(dirs, filepath.ToSlash(rel))
+		return nil
+	})
+	if walkErr != nil {
+		errors = append(errors, walkErr)
+	}
+
+	// Walk visits directories such as Android/ before the OWNERS file next to
+	// them, sort by depth so parents are processed before their children
+	sort.SliceStable(dirs, func(i, j int) bool {
+		return depth(dirs[i]) < depth(dirs[j])
+	})
+	for _, dir := range dirs {
+		f, errs := l.load(path.Join(dir, OwnersFileName), map[string]bool{})
+		errors = append(errors, errs...)
+		if f != nil {
+			entries = append(entries, l.entries(dir, f)...)
+		}
+	}
+	if len(errors) > 0 {
+		return nil, errors
+	}
+
+	index, err := createIndexFromEntries(entries, opts...)
+	if err != nil {
+		return nil, []error{err}
+	}
+	return index, nil
+}
+
+// ownersLoader reads the OWNERS files of a tree
+type ownersLoader struct {
+	root  string
+	files map[string]*ownersFile // parsed files by their path from the root
+	dirs  map[string][]string    // owners of the directories, including the inherited ones
+}
+
+// entries returns the rules for the OWNERS file of a directory. The OWNERS
+// files of its parent directories must have been processed first.
+func (l *ownersLoader) entries(dir string, f *ownersFile) []*Entry {
+	owners := append([]string{}, f.owners...)
+	if !f.noparent {
+		owners = append(owners, l.parentOwners(dir)...)
+	}
+	owners = removeDuplicates(owners)
+	l.dirs[dir] = owners
+
+	prefix := ""
+	entries := []*Entry{}
+	if dir == "." {
+		entries = append(entries, newOwnersEntry("*", false, owners))
+	} else {
+		prefix = dir + "/"
+		entries = append(entries, newOwnersEntry(prefix, true, owners))
+	}
+
+	for _, rule := range f.perFile {
+		perFile := append([]string{}, rule.owners...)
+		if !rule.noparent {
+			perFile = append(perFile, owners...)
+		}
+		entry := newOwnersEntry(prefix+rule.glob, true, removeDuplicates(perFile))
+		entry.line = rule.line
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// parentOwners returns the owners of the closest parent directory with an OWNERS file
+func (l *ownersLoader) parentOwners(dir string) []string {
+	for dir != "." {
+		dir = path.Dir(dir)
+		if owners, ok := l.dirs[dir]; ok {
+			return owners
+		}
+	}
+	return []string{}
+}
+
+// depth returns the number of directories in a slash separated path from the root
+func depth(dir string) int {
+	if dir == "." {
+		return 0
+	}
+	return strings.Count(dir, "/") + 1
+}
+
+func newOwnersEntry(pattern string, anchored bool, owners []string) *Entry {
+	return &Entry{
+		path:     pattern,
+		anchored: anchored,
+		owners:   owners,
+		suffix:   DetermineSuffix(pattern),
+	}
+}
+
+// load parses the OWNERS file at the path relative to the root, following its
+// includes. including holds the files being loaded to detect include cycles.
+func (l *ownersLoader) load(name string, including map[string]bool) (*ownersFile, []error) {
+	if f, ok := l.files[name]; ok {
+		return f, nil
+	}
+	if including[name] {
+		return nil, []error{fmt.Errorf("%s: include cycle", name)}
+	}
+	including[name] = true
+	defer delete(including, name)
+
+	file, err := os.Open(filepath.Join(l.root, filepath.FromSlash(name)))
+	if err != nil {
+		return nil, []error{err}
+	}
+	defer file.Close()
+
+	f := &ownersFile{owners: []string{}, perFile: []*perFileRule{}}
+	errors := []error{}
+	fail := func(line int, format string, a ...interface{}) {
+		errors = append(errors, fmt.Errorf("%s:%d: %s", name, line, fmt.Sprintf(format, a...)))
+	}
+
+	scanner := bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == "":
+		case line == "set noparent":
+			f.noparent = true
+		case strings.HasPrefix(line, "per-file "):
+			i := strings.Index(line, "=")
+			if i < 0 {
+				fail(number, "per-file is missing its owners, expected per-file glob=owners")
+				continue
+			}
+			directive := strings.TrimSpace(line[i+1:])
+			for _, glob := range strings.Split(line[len("per-file "):i], ",") {
+				glob = strings.TrimSpace(glob)
+				if glob == "" || strings.Contains(glob, "/") {
+					fail(number, "(%s) is an invalid per-file glob", glob)
+					continue
+				}
+				rule := f.rule(glob, number)
+				if directive == "set noparent" {
+					rule.noparent = true
+					continue
+				}
+				owners, err := l.owners(name, directive, including)
+				if err != nil {
+					fail(number, "%v", err)
+					continue
+				}
+				rule.owners = append(rule.owners, owners...)
+			}
+		default:
+			owners, err := l.owners(name, line, including)
+			if err != nil {
+				fail(number, "%v", err)
+				continue
+			}
+			f.owners = append(f.owners, owners...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errors = append(errors, fmt.Errorf("%s: %v", name, err))
+	}
+	if len(errors) > 0 {
+		return nil, errors
+	}
+	l.files[name] = f
+	return f, nil
+}
+
+// rule returns the per-file rule of the glob, creating it on first use
+func (f *ownersFile) rule(glob string, line int) *perFileRule {
+	for _, rule := range f.perFile {
+		if rule.glob == glob {
+			return rule
+		}
+	}
+	rule := &perFileRule{glob: glob, owners: []string{}, line: line}
+	f.perFile = append(f.perFile, rule)
+	return rule
+}
+
+// owners returns the owners listed by a directive of the OWNERS file name,
+// either comma separated owners or an include
+func (l *ownersLoader) owners(name string, directive string, including map[string]bool) ([]string, error) {
+	include := ""
+	switch {
+	case strings.HasPrefix(directive, "file://"):
+		include = strings.TrimPrefix(directive, "file://")
+	case strings.HasPrefix(directive, "file:"):
+		include = path.Join(path.Dir(name), strings.TrimPrefix(directive, "file:"))
+	case strings.HasPrefix(directive, "include "):
+		include = strings.TrimSpace(strings.TrimPrefix(directive, "include "))
+		if !strings.HasPrefix(include, "/") {
+			include = path.Join(path.Dir(name), include)
+		}
+	}
+	if include != "" {
+		f, errs := l.load(path.Clean(strings.TrimPrefix(include, "/")), including)
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("cannot include %s: %v", include, errs[0])
+		}
+		return f.owners, nil
+	}
+
+	owners := []string{}
+	for _, owner := range strings.Split(directive, ",") {
+		owner = strings.TrimSpace(owner)
+		if owner != Everyone && !rxEmail.MatchString(owner) {
+			return nil, fmt.Errorf("(%s) is an invalid owner", owner)
+		}
+		owners = append(owners, owner)
+	}
+	return owners, nil
+}
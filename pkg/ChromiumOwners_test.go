@@ -0,0 +1,89 @@
+package codeowners
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildFromOwnersTree(t *testing.T) {
+	co, errs := BuildFromOwnersTree("fixtures/chromium")
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	testcases := map[string][]string{
+		"main.cc":               {"root@example.com"},
+		"README.md":             {"docs@example.com", "root@example.com"},
+		"build/foo.cc":          {"build@example.com", "root@example.com"},
+		"build/args.gni":        {"gn@example.com", "build@example.com", "root@example.com"},
+		"build/BUILD.gn":        {"buildgn@example.com"},
+		"build/linux/BUILD.gn":  {"build@example.com", "root@example.com"},
+		"build/config/BUILD.gn": {"tools@example.com", "config@example.com"},
+		"third_party/lib/a.c":   {Everyone, "root@example.com"},
+		"docs/guide.md":         {"writer@example.com", "root@example.com"},
+		"tools/run.py":          {"tools@example.com", "root@example.com"},
+		"Android/x.java":        {"android@example.com", "root@example.com"},
+	}
+	for path, expected := range testcases {
+		if owners := co.FindOwners(path); !reflect.DeepEqual(owners, expected) {
+			t.Errorf("%s : expected %v got %v", path, expected, owners)
+		}
+	}
+}
+
+func TestBuildFromOwnersTreeErrors(t *testing.T) {
+	testcases := []struct {
+		files    map[string]string
+		expected string
+	}{
+		{
+			files:    map[string]string{"OWNERS": "jane@example.com\nnot an owner\n"},
+			expected: "OWNERS:2: (not an owner) is an invalid owner",
+		},
+		{
+			files:    map[string]string{"OWNERS": "per-file *.gn\n"},
+			expected: "OWNERS:1: per-file is missing its owners",
+		},
+		{
+			files:    map[string]string{"OWNERS": "per-file docs/*.md=jane@example.com\n"},
+			expected: "OWNERS:1: (docs/*.md) is an invalid per-file glob",
+		},
+		{
+			files: map[string]string{
+				"a/OWNERS": "file://b/OWNERS\n",
+				"b/OWNERS": "file://a/OWNERS\n",
+			},
+			expected: "include cycle",
+		},
+		{
+			files:    map[string]string{"OWNERS": "file://missing/OWNERS\n"},
+			expected: "OWNERS:1: cannot include missing/OWNERS",
+		},
+	}
+
+	for _, tc := range testcases {
+		root, err := ioutil.TempDir("", "owners")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, content := range tc.files {
+			filePath := filepath.Join(root, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(filePath, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		co, errs := BuildFromOwnersTree(root)
+		if co != nil || len(errs) == 0 || !strings.Contains(errs[0].Error(), tc.expected) {
+			t.Errorf("expected an error containing %q got %v", tc.expected, errs)
+		}
+		os.RemoveAll(root)
+	}
+}
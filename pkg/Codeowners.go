@@ -1,15 +1,14 @@
 package codeowners
 
 import (
-	"bufio"
 	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
 
-	"io"
 	"log"
 	"strings"
 
@@ -23,35 +22,26 @@ type node struct {
 // CodeOwners search index for a CODEOWNER file
 type CodeOwners struct {
 	*trie.PathTrie
+	patterns        map[string]Matcher
+	owners          map[string]Owner // parsed owners by their raw text
+	aliases         *Aliases
+	directory       OwnerDirectory
+	dialect         Dialect
+	resolution      Resolution
+	caseInsensitive bool
+	entries         int
+	doc             *Document
 }
 
-// BuildEntries ...
+// BuildEntries parses the contents of a CODEOWNERS file, the returned errors are *Diagnostic
 func BuildEntries(input []byte, includeComments bool) ([]*Entry, []error) {
-	entries := []*Entry{}
-	reader := bufio.NewReader(bytes.NewReader(input))
-
-	index := 1
-	errors := []error{}
-	for {
-		line, _, err := reader.ReadLine()
-
-		if err == io.EOF {
-			break
-		}
-		if len(line) < 1 {
-			continue
-		}
+	parsed, errors := NewParser(bytes.NewReader(input)).ParseFile()
 
-		parser := NewParser(strings.NewReader(string(line)))
-		entry, err := parser.Parse()
-		if err != nil {
-			errors = append(errors, fmt.Errorf("Syntax Error On Line %d: %s", index, err.Error()))
-			continue
-		} else if (entry.suffix == PathSufix(None)) && !includeComments {
+	entries := []*Entry{}
+	for _, entry := range parsed {
+		if entry.suffix == PathSufix(None) && !includeComments {
 			continue
 		}
-		index++
-
 		entries = append(entries, entry)
 	}
 	if len(errors) > 0 {
@@ -76,42 +66,101 @@ func newNode() *node {
 }
 
 // BuildFromFile from an file path, absolute or relative, builds the index for the CODEOWNERS file
-func BuildFromFile(filePath string) (*CodeOwners, []error) {
-	entries, errors := BuildEntriesFromFile(filePath, false)
-	if errors != nil {
-		return nil, errors
-	}
-	index, err := createIndexFromEntries(entries)
+func BuildFromFile(filePath string, opts ...Option) (*CodeOwners, []error) {
+	f, err := os.Open(filePath)
 	if err != nil {
 		return nil, []error{err}
 	}
-	return index, nil
+	defer f.Close()
+	return BuildFromReader(f, opts...)
 }
 
-// BuildFromFile from an file path, absolute or relative, builds the index for the CODEOWNERS file
-func BuildIndex(input []byte) (*CodeOwners, []error) {
-	entries, errors := BuildEntries(input, false)
-	if errors != nil {
+// BuildIndex builds the index for the contents of a CODEOWNERS file. The index
+// keeps the layout of the file so that saving it only changes edited lines.
+// When the file only has warnings, such as rules the dialect ignores, the
+// index is returned along with them.
+func BuildIndex(input []byte, opts ...Option) (*CodeOwners, []error) {
+	return BuildFromReader(bytes.NewReader(input), opts...)
+}
+
+// BuildFromReader builds the index for a CODEOWNERS file read from r, see BuildIndex
+func BuildFromReader(r io.Reader, opts ...Option) (*CodeOwners, []error) {
+	t := newCodeOwners(opts...)
+	doc, errors := NewDialectParser(r, t.dialect).ParseDocument()
+	if failed(errors) {
 		return nil, errors
 	}
-	index, err := createIndexFromEntries(entries)
-	if err != nil {
-		return nil, []error{err}
+	t.index(doc)
+	if t.directory != nil {
+		errors = append(errors, t.ValidateOwners(t.directory)...)
+		if failed(errors) {
+			return nil, errors
+		}
+	}
+	if len(errors) > 0 {
+		return t, errors
+	}
+	return t, nil
+}
+
+// failed reports whether any of the errors is more than a warning
+func failed(errors []error) bool {
+	for _, err := range errors {
+		if d, ok := err.(*Diagnostic); !ok || d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildFromRepository builds the index for the CODEOWNERS file of a repository,
+// looked up in the locations of the dialect
+func BuildFromRepository(root string, opts ...Option) (*CodeOwners, []error) {
+	d := newCodeOwners(opts...).dialect
+	for _, location := range d.Locations() {
+		filePath := filepath.Join(root, filepath.FromSlash(location))
+		if _, err := os.Stat(filePath); err == nil {
+			return BuildFromFile(filePath, opts...)
+		}
 	}
-	return index, nil
+	return nil, []error{fmt.Errorf("no CODEOWNERS file found in %s for %s", root, d.Name())}
 }
 
 // createIndexFromEntries ...
-func createIndexFromEntries(entries []*Entry) (*CodeOwners, error) {
+func createIndexFromEntries(entries []*Entry, opts ...Option) (*CodeOwners, error) {
+	return createIndexFromDocument(newDocumentFromEntries(entries), opts...)
+}
+
+// createIndexFromDocument indexes the rules of a document
+func createIndexFromDocument(doc *Document, opts ...Option) (*CodeOwners, error) {
+	t := newCodeOwners(opts...)
+	t.index(doc)
+	return t, nil
+}
+
+// newCodeOwners creates an empty index configured by the options
+func newCodeOwners(opts ...Option) *CodeOwners {
 	t := &CodeOwners{
-		trie.NewPathTrie(),
+		PathTrie: trie.NewPathTrie(),
+		patterns: map[string]Matcher{},
+		owners:   map[string]Owner{},
+		dialect:  Generic,
 	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
 
-	for _, entry := range entries {
+// index adds the rules of a document to the index
+func (t *CodeOwners) index(doc *Document) {
+	t.doc = doc
+	for _, entry := range doc.Entries() {
+		if entry.suffix == PathSufix(None) {
+			continue
+		}
 		t.addOwnerByEntry(entry)
 	}
-
-	return t, nil
 }
 
 func (n *node) addEntry(e *Entry) {
@@ -121,7 +170,8 @@ func (n *node) addEntry(e *Entry) {
 func (t *CodeOwners) addOwnerByEntry(entry *Entry) {
 	var n *node
 	var ok bool
-	value := t.Get(entry.path)
+	path := strings.TrimSuffix(entry.path, "/")
+	value := t.Get(path)
 	if value == nil {
 		n = newNode()
 	} else {
@@ -132,27 +182,59 @@ func (t *CodeOwners) addOwnerByEntry(entry *Entry) {
 		}
 	}
 
-	n.addEntry(entry)
-	path := entry.path
-	if []rune(path)[len(path)-1] == '/' {
-		path = path[:len(path)-1]
+	// Owners are parsed once, as written and as resolved through the aliases
+	entry.aliases = t.aliases
+	for _, owner := range append(entry.Owners(), entry.effectiveOwners()...) {
+		t.owner(owner)
+	}
+	if t.owners == nil {
+		t.owners = map[string]Owner{}
 	}
+	entry.parsed = t.owners
+	t.entries++
+	entry.order = t.entries
+	n.addEntry(entry)
 	t.Put(path, n)
 }
 
 func (t *CodeOwners) AddOwner(path string, owners ...string) {
-	t.addOwnerByEntry(&Entry{
-		path:   path,
-		owners: owners,
-		suffix: DetermineSuffix(path),
-	})
+	anchored := strings.HasPrefix(path, "/")
+	if anchored {
+		path = path[1:]
+	}
+	entry := &Entry{
+		path:     path,
+		anchored: anchored,
+		owners:   owners,
+		suffix:   DetermineSuffix(path),
+		section:  t.document().lastSection(),
+	}
+	t.addOwnerByEntry(entry)
+	t.document().append(entry)
 }
 
+// RemovePath removes the entries for the path, written with or without its
+// leading and trailing slash like the path of AddOwner
 func (t *CodeOwners) RemovePath(path string) {
+	path = strings.TrimSuffix(strings.TrimPrefix(path, "/"), "/")
+	if n, ok := t.Get(path).(*node); ok {
+		t.document().remove(n.entries...)
+	}
 	t.Put(path, nil)
 }
 
+// document returns the document the index was built from
+func (t *CodeOwners) document() *Document {
+	if t.doc == nil {
+		t.doc = newDocument()
+	}
+	return t.doc
+}
+
+// RemoveOwner removes the owner from every entry. Entries left without owners
+// are removed too, rather than turning into entries which clear ownership.
 func (t *CodeOwners) RemoveOwner(owner string) {
+	removed := []*Entry{}
 	walker := func(key string, value interface{}) error {
 		if value == nil {
 			return nil
@@ -161,19 +243,27 @@ func (t *CodeOwners) RemoveOwner(owner string) {
 		if !ok {
 			log.Fatal("Structure of the code owner index is malformed")
 		}
+		entries := []*Entry{}
 		for _, en := range n.entries {
-			newOwners := en.owners
-			for i, o := range en.owners {
-				if o == owner {
-					RemoveIndex(newOwners, i)
+			newOwners := []string{}
+			for _, o := range en.owners {
+				if o != owner {
+					newOwners = append(newOwners, o)
 				}
 			}
+			if len(newOwners) == 0 && len(en.owners) > 0 {
+				removed = append(removed, en)
+				continue
+			}
 			en.owners = newOwners
+			entries = append(entries, en)
 		}
+		n.entries = entries
 
 		return nil
 	}
 	t.Walk(walker)
+	t.document().remove(removed...)
 }
 
 func (t *CodeOwners) ReplaceOwner(oldOwner, newOwner string) {
@@ -190,6 +280,7 @@ func (t *CodeOwners) ReplaceOwner(oldOwner, newOwner string) {
 			for i, o := range en.owners {
 				if o == oldOwner {
 					newOwners[i] = newOwner
+					t.owner(newOwner)
 				}
 			}
 			en.owners = newOwners
@@ -200,9 +291,154 @@ func (t *CodeOwners) ReplaceOwner(oldOwner, newOwner string) {
 	t.Walk(walker)
 }
 
-// FindOwners ...
+// SetResolution changes how FindOwners combines the owners of matching entries
+func (t *CodeOwners) SetResolution(r Resolution) {
+	t.resolution = r
+}
+
+// SetCaseInsensitive changes whether paths are matched regardless of their case
+func (t *CodeOwners) SetCaseInsensitive(caseInsensitive bool) {
+	if t.caseInsensitive != caseInsensitive {
+		t.patterns = map[string]Matcher{}
+	}
+	t.caseInsensitive = caseInsensitive
+}
+
+// FindOwners returns the owners of a path according to the index resolution
 func (t *CodeOwners) FindOwners(path string) []string {
+	return t.FindOwnersWithResolution(path, t.resolution)
+}
+
+// FindOwnersWithResolution returns the owners of a path, with LastMatch only the
+// owners of the last matching entry are returned and with Union the owners of
+// every matching entry. Owners are ordered by the precedence of their entry and
+// then by their position within it.
+func (t *CodeOwners) FindOwnersWithResolution(path string, r Resolution) []string {
+	return ownersOf(contributingEntries(t.matchingEntries(path), r))
+}
+
+// ownersOf returns the owners of the entries in their order, without duplicates
+func ownersOf(entries []*Entry) []string {
 	owners := []string{}
+	for _, en := range entries {
+		owners = append(owners, en.effectiveOwners()...)
+	}
+	return removeDuplicates(owners)
+}
+
+// OwnerGroup holds the owners contributed by a single entry of the index
+type OwnerGroup struct {
+	Path   string
+	Owners []string
+	Parsed []Owner // Owners parsed according to the dialect of the index
+}
+
+// FindParsedOwners returns the owners of a path like FindOwners, parsed
+// according to the dialect of the index
+func (t *CodeOwners) FindParsedOwners(path string) []Owner {
+	return t.parsedOwners(t.FindOwners(path))
+}
+
+// parsedOwners returns the parsed form of raw owners
+func (t *CodeOwners) parsedOwners(raw []string) []Owner {
+	owners := []Owner{}
+	for _, owner := range raw {
+		owners = append(owners, t.owner(owner))
+	}
+	return owners
+}
+
+// owner returns the parsed form of a raw owner, parsing it on first use.
+// Owners the dialect rejects, such as those added with AddOwner, are kept
+// with an UnknownOwner kind.
+func (t *CodeOwners) owner(raw string) Owner {
+	if t.owners == nil {
+		t.owners = map[string]Owner{}
+	}
+	if o, ok := t.owners[raw]; ok {
+		return o
+	}
+	d := t.dialect
+	if d == nil {
+		d = Generic
+	}
+	o := parseKnownOwner(raw, d)
+	t.owners[raw] = o
+	return o
+}
+
+// FindOwnerGroups returns the owners of a path grouped by the entry they come
+// from, ordered by precedence
+func (t *CodeOwners) FindOwnerGroups(path string) []OwnerGroup {
+	groups := []OwnerGroup{}
+	for _, en := range contributingEntries(t.matchingEntries(path), t.resolution) {
+		groups = append(groups, OwnerGroup{
+			Path:   en.rawPath(),
+			Owners: append([]string{}, en.effectiveOwners()...),
+			Parsed: t.parsedOwners(en.effectiveOwners()),
+		})
+	}
+	return groups
+}
+
+// contributingEntries returns the entries giving ownership of a path out of
+// the entries matching it, the entry with the highest precedence first. Each
+// GitLab section is resolved on its own and the entries of every section are
+// combined.
+func contributingEntries(matching []*Entry, r Resolution) []*Entry {
+	sections := map[string][]*Entry{}
+	for _, en := range matching {
+		key := en.section.key()
+		sections[key] = append(sections[key], en)
+	}
+
+	contributing := []*Entry{}
+	for _, matches := range sections {
+		if r == LastMatch {
+			matches = matches[len(matches)-1:]
+		}
+		// An entry without owners clears the ownership given by the entries before it
+		for i := len(matches) - 1; i >= 0; i-- {
+			if len(matches[i].effectiveOwners()) == 0 {
+				matches = matches[i+1:]
+				break
+			}
+		}
+		contributing = append(contributing, matches...)
+	}
+
+	sort.Slice(contributing, func(i, j int) bool {
+		return contributing[i].order > contributing[j].order
+	})
+	return contributing
+}
+
+// Sections returns the GitLab sections of the index in file order
+func (t *CodeOwners) Sections() []*Section {
+	return t.document().Sections()
+}
+
+// DirectoryOwners describes the ownership of a directory
+type DirectoryOwners struct {
+	Directory string
+	Owners    []string // owners of the directory itself
+	Subtree   []string // owners of every entry that can match a path below the directory
+}
+
+// FindDirectoryOwners returns both the owners of a directory and the owners
+// of anything beneath it, ordered by precedence
+func (t *CodeOwners) FindDirectoryOwners(dir string) *DirectoryOwners {
+	dir = strings.Trim(dir, "/")
+	result := &DirectoryOwners{
+		Directory: dir,
+		Owners:    []string{},
+		Subtree:   []string{},
+	}
+	if dir != "" {
+		result.Owners = t.FindOwners(dir + "/")
+	}
+
+	entries := []*Entry{}
 	walker := func(key string, value interface{}) error {
 		if value == nil {
 			return nil
@@ -212,58 +448,89 @@ func (t *CodeOwners) FindOwners(path string) []string {
 			panic("Structure of the index is malformed")
 		}
 		for _, en := range n.entries {
-			if en.suffix == PathSufix(Recursive) || en.suffix == PathSufix(Absolute) || en.suffix == PathSufix(Flat) {
-				owners = append(owners, en.owners...)
+			if en.suffix != PathSufix(None) && t.pattern(en).MatchesBelow(dir) {
+				entries = append(entries, en)
 			}
 		}
-
 		return nil
 	}
-	t.WalkKey(path, walker)
+	t.Walk(walker)
 
-	//get the base wild card type
-	ext := "*" + filepath.Ext(path)
-	extEntry := t.Get(ext)
-	if extEntry != nil {
-		n, ok := extEntry.(*node)
-		if !ok {
-			log.Fatal("Structure of the code owner index is malformed")
-		}
-		for _, en := range n.entries {
-			owners = append(owners, en.owners...)
-		}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].order > entries[j].order
+	})
+	for _, en := range entries {
+		result.Subtree = append(result.Subtree, en.effectiveOwners()...)
 	}
+	result.Subtree = removeDuplicates(result.Subtree)
+	return result
+}
 
-	//get the base wild card
-	ext = "*"
-	extEntry = t.Get(ext)
-	if extEntry != nil {
-		n, ok := extEntry.(*node)
-		if !ok {
-			log.Fatal("Structure of the code owner index is malformed")
-		}
-		for _, en := range n.entries {
-			owners = append(owners, en.owners...)
+// MatchResult explains which entries of the index give ownership of a path
+type MatchResult struct {
+	Path       string
+	Rule       *Entry   // entry with the highest precedence, nil when nothing matches
+	Owners     []string // owners of the path according to the index resolution
+	Parsed     []Owner  // Owners parsed according to the dialect of the index
+	Unowned    bool     // Rule deliberately has no owners and no other section owns the path
+	Overridden []*Entry // matching entries that lost to a later entry of their section, highest precedence first
+
+	// SectionRules holds the last matching entry of every GitLab section, the
+	// entry with the highest precedence first
+	SectionRules []*Entry
+}
+
+// Match returns the entry that applies to a path along with the other
+// matching entries, so callers can explain where ownership comes from
+func (t *CodeOwners) Match(path string) *MatchResult {
+	// The index is searched once, every field is derived from the matches
+	matches := t.matchingEntries(path)
+	contributing := contributingEntries(matches, t.resolution)
+	result := &MatchResult{
+		Path:         path,
+		Owners:       ownersOf(contributing),
+		Overridden:   []*Entry{},
+		SectionRules: []*Entry{},
+	}
+	result.Parsed = t.parsedOwners(result.Owners)
+	if len(matches) == 0 {
+		return result
+	}
+
+	result.Rule = matches[len(matches)-1]
+	result.Unowned = unowned(matches, contributing)
+	seen := map[string]bool{}
+	for i := len(matches) - 1; i >= 0; i-- {
+		// Rules only override the rules of their own section
+		if key := matches[i].section.key(); !seen[key] {
+			seen[key] = true
+			result.SectionRules = append(result.SectionRules, matches[i])
+		} else {
+			result.Overridden = append(result.Overridden, matches[i])
 		}
 	}
-	return removeDuplicatesUnordered(owners)
+	return result
 }
 
-func (t *CodeOwners) Print() {
-	var b bytes.Buffer
-	t.Serialize(&b)
-	fmt.Print(b.String())
+// IsExplicitlyUnowned reports whether the entry that applies to the path is a
+// pattern without owners, meaning the path deliberately has no code owner
+func (t *CodeOwners) IsExplicitlyUnowned(path string) bool {
+	matches := t.matchingEntries(path)
+	return unowned(matches, contributingEntries(matches, t.resolution))
 }
 
-func (co *CodeOwners) SaveToFile(f *os.File) {
-	var b bytes.Buffer
-	co.Serialize(&b)
-	f.WriteString(b.String())
-	f.Sync()
+// unowned reports whether the last of the matching entries has no owners and
+// none of the contributing entries, in any section, gives ownership
+func unowned(matches []*Entry, contributing []*Entry) bool {
+	if len(matches) == 0 || len(matches[len(matches)-1].effectiveOwners()) > 0 {
+		return false
+	}
+	return len(contributing) == 0
 }
 
-func (t *CodeOwners) Serialize(b *bytes.Buffer) {
-	toSort := []string{}
+// matchingEntries returns every entry matching the path, ordered by precedence
+func (t *CodeOwners) matchingEntries(path string) []*Entry {
+	matches := []*Entry{}
 	walker := func(key string, value interface{}) error {
 		if value == nil {
 			return nil
@@ -272,34 +539,71 @@ func (t *CodeOwners) Serialize(b *bytes.Buffer) {
 		if !ok {
 			panic("Structure of the index is malformed")
 		}
-
 		for _, en := range n.entries {
-			if en.comment != "" {
-				toSort = append(toSort, fmt.Sprintf("%s %s #%s", en.path, strings.Join(en.owners, " "), en.comment))
+			if en.suffix == PathSufix(None) {
+				continue
+			}
+			if t.pattern(en).Match(path) {
+				matches = append(matches, en)
 			}
-			toSort = append(toSort, fmt.Sprintf("%s %s", en.path, strings.Join(en.owners, " ")))
 		}
 
 		return nil
 	}
 	t.Walk(walker)
-	sort.Strings(toSort)
 
-	b.WriteString(strings.Join(toSort, "\n"))
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].order < matches[j].order
+	})
+	return matches
 }
 
-func removeDuplicatesUnordered(elements []string) []string {
-	encountered := map[string]bool{}
-
-	// Create a map of all unique elements.
-	for v := range elements {
-		encountered[elements[v]] = true
+// pattern returns the compiled pattern of an entry, compiling it on first use
+func (t *CodeOwners) pattern(en *Entry) Matcher {
+	if t.patterns == nil {
+		t.patterns = map[string]Matcher{}
+	}
+	if t.dialect == nil {
+		t.dialect = Generic
 	}
+	raw := en.rawPath()
+	p, ok := t.patterns[raw]
+	if !ok {
+		p = t.dialect.CompilePattern(raw, t.caseInsensitive)
+		t.patterns[raw] = p
+	}
+	return p
+}
+
+func (t *CodeOwners) Print() {
+	var b bytes.Buffer
+	t.Serialize(&b)
+	fmt.Print(b.String())
+}
+
+func (co *CodeOwners) SaveToFile(f *os.File) {
+	var b bytes.Buffer
+	co.Serialize(&b)
+	f.WriteString(b.String())
+	f.Sync()
+}
+
+// Serialize writes the CODEOWNERS file of the index, lines keep their
+// original order and formatting unless they were edited
+func (t *CodeOwners) Serialize(b *bytes.Buffer) {
+	t.document().writeTo(b)
+}
+
+// removeDuplicates removes repeated elements, keeping the first occurrence
+func removeDuplicates(elements []string) []string {
+	encountered := map[string]bool{}
 
-	// Place all keys from the map into a slice.
 	result := []string{}
-	for key := range encountered {
-		result = append(result, key)
+	for _, element := range elements {
+		if !encountered[element] {
+			encountered[element] = true
+			result = append(result, element)
+		}
 	}
 	return result
 }
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -16,54 +17,73 @@ func TestBuildEntriesFromFile(t *testing.T) {
 			comment: "",
 			suffix:  PathSufix(Flat),
 			owners:  []string{"@default-codeowner"},
+			line:    8,
 		},
 		&Entry{
 			path:    "*.rb",
 			comment: "",
 			suffix:  PathSufix(Type),
 			owners:  []string{"@ruby-owner"},
+			line:    13,
 		},
 		&Entry{
 			path:    "\\#file_with_pound.rb",
 			comment: "",
 			suffix:  PathSufix(Absolute),
 			owners:  []string{"@owner-file-with-pound"},
+			line:    16,
 		},
 		&Entry{
 			path:    "CODEOWNERS",
 			comment: "",
 			suffix:  PathSufix(Absolute),
 			owners:  []string{"@multiple", "@code", "@owners"},
+			line:    19,
 		},
 		&Entry{
 			path:    "README",
 			comment: "",
 			suffix:  PathSufix(Absolute),
 			owners:  []string{"@group", "@group/with-nested/subgroup"},
+			line:    29,
 		},
 		&Entry{
-			path:    "docs/",
-			comment: "",
-			suffix:  PathSufix(Recursive),
-			owners:  []string{"@all-docs"},
+			path:     "docs/",
+			anchored: true,
+			comment:  "",
+			suffix:   PathSufix(Recursive),
+			owners:   []string{"@all-docs"},
+			line:     33,
 		},
 		&Entry{
-			path:    "docs/*",
-			comment: "",
-			suffix:  PathSufix(Flat),
-			owners:  []string{"@root-docs"},
+			path:     "docs/*",
+			anchored: true,
+			comment:  "",
+			suffix:   PathSufix(Flat),
+			owners:   []string{"@root-docs"},
+			line:     38,
 		},
 		&Entry{
 			path:    "lib/",
 			comment: "",
 			suffix:  PathSufix(Recursive),
 			owners:  []string{"@lib-owner"},
+			line:    42,
 		},
 		&Entry{
-			path:    "config/",
+			path:     "config/",
+			anchored: true,
+			comment:  "",
+			suffix:   PathSufix(Recursive),
+			owners:   []string{"@config-owner"},
+			line:     46,
+		},
+		&Entry{
+			path:    "path\\ with\\ spaces/",
 			comment: "",
 			suffix:  PathSufix(Recursive),
-			owners:  []string{"@config-owner"},
+			owners:  []string{"@space-owner"},
+			line:    49,
 		},
 	}
 
@@ -74,8 +94,8 @@ func TestBuildEntriesFromFile(t *testing.T) {
 		t.FailNow()
 	}
 
-	if errors[0].Error() != "Syntax Error On Line 5: (this_does_not_match) is an invalid owner" {
-		t.Fatalf("Expected error \n%s but got \n%s", "Error On Line 5: (this_does_not_match) is an invalid owner", errors[0].Error())
+	if errors[0].Error() != "Syntax Error On Line 25: (this_does_not_match) is an invalid owner" {
+		t.Fatalf("Expected error \n%s but got \n%s", "Error On Line 25: (this_does_not_match) is an invalid owner", errors[0].Error())
 		t.FailNow()
 	}
 	if len(entries) != len(outputs) {
@@ -91,12 +111,43 @@ func TestBuildEntriesFromFile(t *testing.T) {
 	}
 }
 
+func TestBuildEntriesDiagnostics(t *testing.T) {
+	input := "# owners\r\n\r\n*.js @frontend\r\n\t/docs/  @docs  not-an-owner # docs\r\n@orphan\r\nREADME @legal üser\n"
+	_, errors := BuildEntries([]byte(input), false)
+	if len(errors) != 3 {
+		t.Fatalf("expected 3 errors got %v", errors)
+	}
+
+	expected := []Diagnostic{
+		{Line: 4, Column: 17, Start: 44, End: 56, Severity: SeverityError, Code: CodeInvalidOwner, Message: "(not-an-owner) is an invalid owner"},
+		{Line: 5, Column: 1, Start: 65, End: 72, Severity: SeverityError, Code: CodeMissingPath, Message: "Missing path for entry"},
+		{Line: 6, Column: 15, Start: 88, End: 93, Severity: SeverityError, Code: CodeInvalidOwner, Message: "(üser) is an invalid owner"},
+	}
+	tokens := []string{"not-an-owner", "@orphan", "üser"}
+	for i, err := range errors {
+		d, ok := err.(*Diagnostic)
+		if !ok {
+			t.Fatalf("expected a *Diagnostic got %T", err)
+		}
+		if !reflect.DeepEqual(*d, expected[i]) {
+			t.Errorf("expected %+v got %+v", expected[i], *d)
+		}
+		if input[d.Start:d.End] != tokens[i] {
+			t.Errorf("expected the span to cover %s got %s", tokens[i], input[d.Start:d.End])
+		}
+	}
+	if errors[0].Error() != "Syntax Error On Line 4: (not-an-owner) is an invalid owner" {
+		t.Errorf("unexpected error message %s", errors[0].Error())
+	}
+}
+
 func TestBuildFromFile(t *testing.T) {
 	co, err := BuildFromFile("fixtures/testCODEOWNERS_Example")
 	if err != nil {
 		t.Fatalf("expecting a non error")
 		t.FailNow()
 	}
+	co.SetResolution(Union)
 	testcases := []struct {
 		input    string
 		expected []string
@@ -148,6 +199,7 @@ func TestBuildFromFileWildCard(t *testing.T) {
 		t.Fatalf("expecting a non error")
 		t.FailNow()
 	}
+	co.SetResolution(Union)
 	testcases := []struct {
 		input    string
 		expected []string
@@ -199,6 +251,452 @@ func TestBuildFromFileWildCard(t *testing.T) {
 	}
 }
 
+func TestFindOwnersLastMatch(t *testing.T) {
+	co, err := BuildFromFile("fixtures/testCODEOWNERS_Example_Wildcard")
+	if err != nil {
+		t.Fatalf("expecting a non error")
+		t.FailNow()
+	}
+	testcases := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			input:    "app/lib/network",
+			expected: []string{"@c"},
+		},
+		{
+			input:    "app/vendor/package.json",
+			expected: []string{"@b"},
+		},
+		{
+			input:    "app/vendor/hooli/middle_out.go",
+			expected: []string{"@richard"},
+		},
+		{
+			input:    "app/vendor/hooli/index.js",
+			expected: []string{"@mike"},
+		},
+		{
+			input:    "app/vendor/hooli/server.js",
+			expected: []string{"@frontend"},
+		},
+		{
+			input:    "Makefile",
+			expected: []string{"@devs"},
+		},
+	}
+
+	for _, tc := range testcases {
+		if out := co.FindOwners(tc.input); !sameStringSlice(out, tc.expected) {
+			t.Errorf("%s : expected %v got %v", tc.input, tc.expected, out)
+		}
+		if out := co.FindOwnersWithResolution(tc.input, LastMatch); !sameStringSlice(out, tc.expected) {
+			t.Errorf("%s : expected %v got %v", tc.input, tc.expected, out)
+		}
+	}
+
+	co.SetResolution(Union)
+	if out := co.FindOwners("app/vendor/hooli/server.js"); !sameStringSlice(out, []string{"@devs", "@a", "@c", "@frontend"}) {
+		t.Errorf("expected the union of every matching entry got %v", out)
+	}
+}
+
+func TestAnchoredEntries(t *testing.T) {
+	co, err := BuildIndex([]byte("lib/ @lib-owner\n/config/ @config-owner\n"))
+	if err != nil {
+		t.Fatalf("expecting a non error %v", err)
+	}
+	testcases := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "lib/index.js", expected: []string{"@lib-owner"}},
+		{input: "app/lib/index.js", expected: []string{"@lib-owner"}},
+		{input: "config/app.yml", expected: []string{"@config-owner"}},
+		{input: "app/config/app.yml", expected: []string{}},
+	}
+	for _, tc := range testcases {
+		if out := co.FindOwners(tc.input); !sameStringSlice(out, tc.expected) {
+			t.Errorf("%s : expected %v got %v", tc.input, tc.expected, out)
+		}
+	}
+
+	co.AddOwner("/vendor/", "@vendor-owner")
+	if out := co.FindOwners("app/vendor/index.js"); len(out) != 0 {
+		t.Errorf("expected an anchored entry to only match from the root got %v", out)
+	}
+
+	var b bytes.Buffer
+	co.Serialize(&b)
+	expected := "lib/ @lib-owner\n/config/ @config-owner\n/vendor/ @vendor-owner\n"
+	if b.String() != expected {
+		t.Errorf("expected \n%s\n got \n%s", expected, b.String())
+	}
+}
+
+func TestEscapedEntries(t *testing.T) {
+	// The fixture contains an invalid owner, index the entries that did parse
+	entries, _ := BuildEntriesFromFile("fixtures/testCODEOWNERS_Rules", false)
+	co, _ := createIndexFromEntries(entries)
+	testcases := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "#file_with_pound.rb", expected: []string{"@owner-file-with-pound"}},
+		{input: "path with spaces/index.md", expected: []string{"@space-owner"}},
+		{input: "app/path with spaces/index.md", expected: []string{"@space-owner"}},
+	}
+	for _, tc := range testcases {
+		if out := co.FindOwners(tc.input); !sameStringSlice(out, tc.expected) {
+			t.Errorf("%s : expected %v got %v", tc.input, tc.expected, out)
+		}
+	}
+
+	co.AddOwner("my docs/*", "@docs")
+	if out := co.FindOwners("my docs/index.md"); !sameStringSlice(out, []string{"@docs"}) {
+		t.Errorf("expected an added entry with spaces to match got %v", out)
+	}
+
+	var b bytes.Buffer
+	co.Serialize(&b)
+	for _, line := range []string{"\\#file_with_pound.rb @owner-file-with-pound", "path\\ with\\ spaces/ @space-owner", "my\\ docs/* @docs"} {
+		if !contains(line, strings.Split(b.String(), "\n")...) {
+			t.Errorf("expected serialized output to contain %s got \n%s", line, b.String())
+		}
+	}
+}
+
+func TestOwnerlessEntries(t *testing.T) {
+	co, err := BuildIndex([]byte(`* @devs
+docs/ @docs
+docs/generated/
+docs/generated/README.md @docs-lead
+`))
+	if err != nil {
+		t.Fatalf("expecting a non error %v", err)
+	}
+	testcases := []struct {
+		input      string
+		resolution Resolution
+		expected   []string
+		unowned    bool
+	}{
+		{input: "docs/index.md", resolution: LastMatch, expected: []string{"@docs"}},
+		{input: "docs/generated/api.md", resolution: LastMatch, expected: []string{}, unowned: true},
+		{input: "docs/generated/api.md", resolution: Union, expected: []string{}, unowned: true},
+		{input: "docs/generated/README.md", resolution: LastMatch, expected: []string{"@docs-lead"}},
+		{input: "docs/generated/README.md", resolution: Union, expected: []string{"@docs-lead"}},
+		{input: "main.go", resolution: Union, expected: []string{"@devs"}},
+	}
+	for _, tc := range testcases {
+		if out := co.FindOwnersWithResolution(tc.input, tc.resolution); !sameStringSlice(out, tc.expected) {
+			t.Errorf("%s : expected %v got %v", tc.input, tc.expected, out)
+		}
+		if unowned := co.IsExplicitlyUnowned(tc.input); unowned != tc.unowned {
+			t.Errorf("%s : expected unowned to be %v", tc.input, tc.unowned)
+		}
+	}
+
+	var b bytes.Buffer
+	co.Serialize(&b)
+	if !contains("docs/generated/", strings.Split(b.String(), "\n")...) {
+		t.Errorf("expected the ownerless entry to be serialized got \n%s", b.String())
+	}
+}
+
+func TestFindOwnersOrder(t *testing.T) {
+	co, err := BuildFromFile("fixtures/testCODEOWNERS_Example_Wildcard")
+	if err != nil {
+		t.Fatalf("expecting a non error")
+		t.FailNow()
+	}
+	co.AddOwner("app/vendor/hooli/index.js", "@zed", "@mike", "@amy")
+
+	expected := []string{"@zed", "@mike", "@amy", "@frontend", "@c", "@a", "@devs"}
+	for i := 0; i < 10; i++ {
+		if out := co.FindOwnersWithResolution("app/vendor/hooli/index.js", Union); !reflect.DeepEqual(out, expected) {
+			t.Fatalf("expected %v got %v", expected, out)
+		}
+	}
+
+	co.SetResolution(Union)
+	groups := co.FindOwnerGroups("app/vendor/hooli/index.js")
+	expectedGroups := []OwnerGroup{
+		{Path: "app/vendor/hooli/index.js", Owners: []string{"@zed", "@mike", "@amy"}},
+		{Path: "app/vendor/hooli/index.js", Owners: []string{"@mike"}},
+		{Path: "*.js", Owners: []string{"@frontend"}},
+		{Path: "app/vendor/hooli/", Owners: []string{"@c"}},
+		{Path: "app/", Owners: []string{"@a"}},
+		{Path: "*", Owners: []string{"@devs"}},
+	}
+	for i := range expectedGroups {
+		expectedGroups[i].Parsed = co.parsedOwners(expectedGroups[i].Owners)
+	}
+	if !reflect.DeepEqual(groups, expectedGroups) {
+		t.Errorf("expected %v got %v", expectedGroups, groups)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	co, err := BuildIndex([]byte(`# Default owners
+* @devs
+
+app/ @a # application code
+app/lib/ @b
+app/lib/generated/
+`))
+	if err != nil {
+		t.Fatalf("expecting a non error %v", err)
+	}
+
+	result := co.Match("app/lib/network.go")
+	if result.Rule == nil {
+		t.Fatalf("expected a matching rule")
+	}
+	if result.Rule.Pattern() != "app/lib/" || result.Rule.Line() != 5 || result.Rule.Suffix() != PathSufix(Recursive) {
+		t.Errorf("unexpected rule %s on line %d", result.Rule.Pattern(), result.Rule.Line())
+	}
+	if !reflect.DeepEqual(result.Owners, []string{"@b"}) || result.Unowned {
+		t.Errorf("unexpected owners %v", result.Owners)
+	}
+	if len(result.Overridden) != 2 {
+		t.Fatalf("expected 2 overridden rules got %d", len(result.Overridden))
+	}
+	if o := result.Overridden[0]; o.Pattern() != "app/" || o.Line() != 4 || o.Comment() != "# application code" {
+		t.Errorf("unexpected overridden rule %s on line %d %s", o.Pattern(), o.Line(), o.Comment())
+	}
+	if o := result.Overridden[1]; o.Pattern() != "*" || o.Line() != 2 || !reflect.DeepEqual(o.Owners(), []string{"@devs"}) {
+		t.Errorf("unexpected overridden rule %s on line %d", o.Pattern(), o.Line())
+	}
+
+	result = co.Match("app/lib/generated/api.go")
+	if !result.Unowned || result.Rule.Line() != 6 || len(result.Owners) != 0 {
+		t.Errorf("expected app/lib/generated/ to be explicitly unowned")
+	}
+
+	co.RemovePath("*")
+	result = co.Match("README.md")
+	if result.Rule != nil || len(result.Overridden) != 0 || len(result.Owners) != 0 {
+		t.Errorf("expected no matching rule got %v", result.Rule)
+	}
+}
+
+func TestMatchSections(t *testing.T) {
+	co, errs := BuildIndex([]byte("[A]\n*.md @a\n[B]\ndocs/ @b\n"))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	result := co.Match("docs/x.md")
+	if len(result.Overridden) != 0 || len(result.SectionRules) != 2 {
+		t.Errorf("expected the rule of every section to apply got %v overridden", result.Overridden)
+	}
+
+	// A rule without owners only clears the ownership of its own section
+	co, errs = BuildIndex([]byte("[A]\n*.md @a\n[B]\ndocs/\n"))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	result = co.Match("docs/x.md")
+	if result.Unowned || !reflect.DeepEqual(result.Owners, []string{"@a"}) {
+		t.Errorf("expected docs/x.md to be owned by @a got %v", result.Owners)
+	}
+	if co.IsExplicitlyUnowned("docs/x.md") {
+		t.Error("expected docs/x.md not to be explicitly unowned")
+	}
+	if !co.IsExplicitlyUnowned("docs/x.go") {
+		t.Error("expected docs/x.go to be explicitly unowned")
+	}
+}
+
+func TestCaseInsensitiveIndex(t *testing.T) {
+	input := []byte("README @legal\n*.MD @docs\n/App/ @a\n")
+	co, err := BuildIndex(input, WithCaseInsensitive())
+	if err != nil {
+		t.Fatalf("expecting a non error %v", err)
+	}
+	testcases := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "readme", expected: []string{"@legal"}},
+		{input: "docs/index.md", expected: []string{"@docs"}},
+		{input: "APP/index.js", expected: []string{"@a"}},
+	}
+	for _, tc := range testcases {
+		if out := co.FindOwners(tc.input); !sameStringSlice(out, tc.expected) {
+			t.Errorf("%s : expected %v got %v", tc.input, tc.expected, out)
+		}
+	}
+
+	co.SetCaseInsensitive(false)
+	if out := co.FindOwners("readme"); len(out) != 0 {
+		t.Errorf("expected a case sensitive lookup to miss got %v", out)
+	}
+
+	co, err = BuildFromFile("fixtures/testCODEOWNERS_Example_Wildcard", WithCaseInsensitive())
+	if err != nil {
+		t.Fatalf("expecting a non error %v", err)
+	}
+	if out := co.FindOwners("App/Vendor/Hooli/Index.JS"); !sameStringSlice(out, []string{"@mike"}) {
+		t.Errorf("expected @mike got %v", out)
+	}
+}
+
+func TestDirectoryScopedEntries(t *testing.T) {
+	co, err := BuildIndex([]byte(`*.md @writers
+docs/*.md @docs
+docs/api/** @api
+/guides/**/*.md @guides
+app/*/ @app-modules
+`))
+	if err != nil {
+		t.Fatalf("expecting a non error %v", err)
+	}
+	testcases := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "README.md", expected: []string{"@writers"}},
+		{input: "docs/index.md", expected: []string{"@docs"}},
+		{input: "docs/index.go", expected: []string{}},
+		{input: "docs/tutorials/index.md", expected: []string{"@writers"}},
+		{input: "vendor/docs/index.md", expected: []string{"@writers"}},
+		{input: "docs/api/v1/users.md", expected: []string{"@api"}},
+		{input: "guides/index.md", expected: []string{"@guides"}},
+		{input: "guides/setup/linux/index.md", expected: []string{"@guides"}},
+		{input: "guides/setup/linux/install.sh", expected: []string{}},
+		{input: "app/payments/index.js", expected: []string{"@app-modules"}},
+		{input: "app/index.js", expected: []string{}},
+	}
+	for _, tc := range testcases {
+		if out := co.FindOwners(tc.input); !sameStringSlice(out, tc.expected) {
+			t.Errorf("%s : expected %v got %v", tc.input, tc.expected, out)
+		}
+	}
+
+	co.AddOwner("app/lib/*.php", "@php")
+	if out := co.FindOwners("app/lib/index.php"); !sameStringSlice(out, []string{"@php"}) {
+		t.Errorf("expected @php got %v", out)
+	}
+	if out := co.FindOwners("app/lib/concurrency/index.php"); !sameStringSlice(out, []string{"@app-modules"}) {
+		t.Errorf("expected @app-modules got %v", out)
+	}
+}
+
+func TestFindDirectoryOwners(t *testing.T) {
+	co, err := BuildFromFile("fixtures/testCODEOWNERS_Example_Wildcard")
+	if err != nil {
+		t.Fatalf("expecting a non error")
+		t.FailNow()
+	}
+	testcases := []struct {
+		input   string
+		owners  []string
+		subtree []string
+	}{
+		{
+			input:   "app/vendor/",
+			owners:  []string{"@a"},
+			subtree: []string{"@mike", "@frontend", "@legal", "@richard", "@c", "@b", "@a", "@devs"},
+		},
+		{
+			input:   "app/lib",
+			owners:  []string{"@b"},
+			subtree: []string{"@frontend", "@legal", "@c", "@b", "@a", "@devs"},
+		},
+		{
+			input:   "app/vendor/hooli",
+			owners:  []string{"@c"},
+			subtree: []string{"@mike", "@frontend", "@legal", "@richard", "@c", "@a", "@devs"},
+		},
+		{
+			input:   "",
+			owners:  []string{},
+			subtree: []string{"@mike", "@frontend", "@legal", "@richard", "@c", "@b", "@a", "@devs"},
+		},
+	}
+	for _, tc := range testcases {
+		out := co.FindDirectoryOwners(tc.input)
+		if !reflect.DeepEqual(out.Owners, tc.owners) {
+			t.Errorf("%s : expected owners %v got %v", tc.input, tc.owners, out.Owners)
+		}
+		if !reflect.DeepEqual(out.Subtree, tc.subtree) {
+			t.Errorf("%s : expected subtree owners %v got %v", tc.input, tc.subtree, out.Subtree)
+		}
+	}
+}
+
+func TestGitLabSections(t *testing.T) {
+	co, err := BuildFromFile("fixtures/testCODEOWNERS_GitLab")
+	if err != nil {
+		t.Fatalf("expecting a non error %v", err)
+	}
+
+	sections := co.Sections()
+	expected := []*Section{
+		{Name: "Documentation", DefaultOwners: []string{"@docs-team"}, line: 5},
+		{Name: "Optional Section", Optional: true, DefaultOwners: []string{}, line: 10},
+		{Name: "Database", Approvals: 2, DefaultOwners: []string{"@dba-team", "@dba-lead"}, line: 13},
+		{Name: "documentation", DefaultOwners: []string{}, line: 17},
+	}
+	if !reflect.DeepEqual(sections, expected) {
+		t.Fatalf("expected sections %v got %v", expected, sections)
+	}
+
+	testcases := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "app/models/user.rb", expected: []string{"@ruby-owner"}},
+		{input: "docs/index.html", expected: []string{"@docs-team", "@default-owner"}},
+		{input: "docs/index.md", expected: []string{"@markdown-reviewers", "@docs-team", "@default-owner"}},
+		{input: "docs/internal/setup.md", expected: []string{"@markdown-reviewers", "@internal-docs", "@default-owner"}},
+		{input: "db/schema.rb", expected: []string{"@dba-team", "@dba-lead", "@ruby-owner"}},
+		{input: "db/migrations/001_init.rb", expected: []string{"@migrations", "@ruby-owner"}},
+		{input: "guides/setup.txt", expected: []string{"@guides-team", "@default-owner"}},
+		{input: "guides/Makefile", expected: []string{"@build-team", "@default-owner"}},
+	}
+	for _, tc := range testcases {
+		if out := co.FindOwners(tc.input); !reflect.DeepEqual(out, tc.expected) {
+			t.Errorf("%s : expected %v got %v", tc.input, tc.expected, out)
+		}
+	}
+
+	result := co.Match("docs/internal/setup.md")
+	if len(result.SectionRules) != 3 {
+		t.Fatalf("expected a rule for 3 sections got %d", len(result.SectionRules))
+	}
+	if s := result.SectionRules[0].Section(); s == nil || !s.Optional || s.Name != "Optional Section" {
+		t.Errorf("expected the optional section to win first got %v", s)
+	}
+	if s := result.SectionRules[2].Section(); s != nil {
+		t.Errorf("expected the last rule to be outside of sections got %v", s)
+	}
+
+	co.AddOwner("db/seeds/", "@seeds")
+	if en := co.Match("db/seeds/users.sql").Rule; en.Section() == nil || en.Section().Name != "documentation" {
+		t.Errorf("expected an added entry to belong to the last section")
+	}
+
+	input, _ := ioutil.ReadFile("fixtures/testCODEOWNERS_GitLab")
+	co, _ = BuildIndex(input)
+	var b bytes.Buffer
+	co.Serialize(&b)
+	if b.String() != string(input) {
+		t.Errorf("expected a byte identical output got \n%s", b.String())
+	}
+
+	entries, _ := BuildEntries(input, false)
+	co, _ = createIndexFromEntries(entries)
+	b.Reset()
+	co.Serialize(&b)
+	if !strings.Contains(b.String(), "*.rb @ruby-owner\n[Documentation] @docs-team\ndocs/\n") ||
+		!strings.Contains(b.String(), "^[Optional Section]\n*.md @markdown-reviewers\n[Database][2] @dba-team @dba-lead\n") {
+		t.Errorf("expected section headers to be written got \n%s", b.String())
+	}
+}
+
 func TestAddOwner(t *testing.T) {
 	co, err := BuildFromFile("fixtures/testCODEOWNERS_Example_Wildcard")
 	if err != nil {
@@ -286,6 +784,7 @@ func TestRemoveOwner(t *testing.T) {
 		t.Fatalf("expecting a non error")
 		t.FailNow()
 	}
+	co.SetResolution(Union)
 	foo := co.FindOwners("app/lib/network/foo.php")
 	if sameStringSlice(foo, []string{"@a", "@b", "@c"}) {
 		t.Errorf("not expected owners for foo.php")
@@ -297,17 +796,41 @@ func TestRemoveOwner(t *testing.T) {
 	}
 
 	foo = co.FindOwners("/app/vendor/hooli/middle_out.go")
-	if !sameStringSlice(foo, []string{"@devs"}) {
+	if !sameStringSlice(foo, []string{"@a", "@richard", "@devs"}) {
 		t.Errorf("not expected owners for foo.php")
 	}
 }
 
+func TestRemoveLastOwner(t *testing.T) {
+	co, errs := BuildIndex([]byte("* @devs\napp/ @a\napp/vendor/ @c\nassets/\n"))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	co.RemoveOwner("@c")
+
+	// The rule lost its only owner and is removed, app/ owns the vendored files again
+	if owners := co.FindOwners("app/vendor/lib.go"); !reflect.DeepEqual(owners, []string{"@a"}) {
+		t.Errorf("expected the owners of app/ got %v", owners)
+	}
+	// Rules written without owners keep clearing ownership
+	if !co.IsExplicitlyUnowned("assets/logo.png") {
+		t.Error("expected assets/ to stay unowned")
+	}
+
+	var b bytes.Buffer
+	co.Serialize(&b)
+	if b.String() != "* @devs\napp/ @a\nassets/\n" {
+		t.Errorf("expected the rule to be removed from the file got %q", b.String())
+	}
+}
+
 func TestRemovePath(t *testing.T) {
 	co, err := BuildFromFile("fixtures/testCODEOWNERS_Example_Wildcard")
 	if err != nil {
 		t.Fatalf("expecting a non error")
 		t.FailNow()
 	}
+	co.SetResolution(Union)
 
 	foo := co.FindOwners("app/vendor/hooli/middle_out.go")
 
@@ -326,6 +849,25 @@ func TestRemovePath(t *testing.T) {
 	if !sameStringSlice(foo, []string{"@a", "@c"}) {
 		t.Errorf("not expected owners for foo.php %v", foo)
 	}
+
+	// Paths are written like the rules, with a leading or trailing slash
+	co, errs := BuildIndex([]byte("* @devs\n/vendor/ @vendor\ndocs/ @docs\n"))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	co.RemovePath("/vendor/")
+	co.RemovePath("docs/")
+	if owners := co.FindOwners("vendor/lib.go"); !reflect.DeepEqual(owners, []string{"@devs"}) {
+		t.Errorf("expected /vendor/ to be removed got %v", owners)
+	}
+	if owners := co.FindOwners("docs/index.md"); !reflect.DeepEqual(owners, []string{"@devs"}) {
+		t.Errorf("expected docs/ to be removed got %v", owners)
+	}
+	var b bytes.Buffer
+	co.Serialize(&b)
+	if b.String() != "* @devs\n" {
+		t.Errorf("expected the rules to be removed from the file got %q", b.String())
+	}
 }
 
 func TestReplaceOwner(t *testing.T) {
@@ -334,6 +876,7 @@ func TestReplaceOwner(t *testing.T) {
 		t.Fatalf("expecting a non error")
 		t.FailNow()
 	}
+	co.SetResolution(Union)
 
 	//base
 	foo := co.FindOwners("app/vendor/hooli/middle_out.go")
@@ -363,16 +906,21 @@ func TestPrint(t *testing.T) {
 	var b bytes.Buffer
 	co.Serialize(&b)
 	expected := `* @devs
-*.js @frontend
-README @legal
+
 app/ @a
 app/lib/ @b
 app/lib/network/ @c
+
 app/vendor/* @b
 app/vendor/hooli/ @c
+app/vendor/hooli/middle_out.go @richard
+
+README @legal
+
+*.js @frontend
+
 app/vendor/hooli/index.js @mike
-app/vendor/hooli/index.react.js @mike
-app/vendor/hooli/middle_out.go @richard`
+app/vendor/hooli/index.react.js @mike`
 	output := b.String()
 	if expected != output {
 		t.Fatalf(output)
@@ -380,6 +928,44 @@ app/vendor/hooli/middle_out.go @richard`
 	}
 }
 
+func TestSerializeRoundTrip(t *testing.T) {
+	input := "\xef\xbb\xbf# Owners of the repository\r\n" +
+		"\r\n" +
+		"*       @devs   # everyone\r\n" +
+		"\t/docs/ @docs @writers\r\n" +
+		"\r\n" +
+		"# Ownership of the generated code\r\n" +
+		"app/generated/\r\n" +
+		"app/  @a\r\n"
+	co, err := BuildIndex([]byte(input))
+	if err != nil {
+		t.Fatalf("expecting a non error %v", err)
+	}
+
+	var b bytes.Buffer
+	co.Serialize(&b)
+	if b.String() != input {
+		t.Fatalf("expected a byte identical output got \n%q", b.String())
+	}
+
+	co.ReplaceOwner("@writers", "@editors")
+	co.AddOwner("*.go", "@gophers")
+	co.RemovePath("app")
+	expected := "\xef\xbb\xbf# Owners of the repository\r\n" +
+		"\r\n" +
+		"*       @devs   # everyone\r\n" +
+		"\t/docs/ @docs @editors\r\n" +
+		"\r\n" +
+		"# Ownership of the generated code\r\n" +
+		"app/generated/\r\n" +
+		"*.go @gophers\r\n"
+	b.Reset()
+	co.Serialize(&b)
+	if b.String() != expected {
+		t.Fatalf("expected only the edited lines to change got \n%q", b.String())
+	}
+}
+
 func TestSave(t *testing.T) {
 	co, err := BuildFromFile("fixtures/testCODEOWNERS_Example_Wildcard")
 	if err != nil {
@@ -396,16 +982,21 @@ func TestSave(t *testing.T) {
 	dat, _ := ioutil.ReadFile(tmpFile.Name())
 
 	expected := `* @devs
-*.js @frontend
-README @legal
+
 app/ @a
 app/lib/ @b
 app/lib/network/ @c
+
 app/vendor/* @b
 app/vendor/hooli/ @c
+app/vendor/hooli/middle_out.go @richard
+
+README @legal
+
+*.js @frontend
+
 app/vendor/hooli/index.js @mike
-app/vendor/hooli/index.react.js @mike
-app/vendor/hooli/middle_out.go @richard`
+app/vendor/hooli/index.react.js @mike`
 	output := string(dat)
 	if expected != output {
 		t.Fatalf(output)
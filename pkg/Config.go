@@ -0,0 +1,33 @@
+package codeowners
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// decodeConfigFile reads a configuration file, such as a roster, into v. The
+// file is read as JSON when its name ends in .json and as YAML otherwise,
+// either way unknown fields are rejected.
+func decodeConfigFile(filePath string, v interface{}) error {
+	input, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(filePath), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(input))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(v)
+	} else {
+		err = yaml.UnmarshalStrict(input, v)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", filePath, err)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package codeowners
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecodeConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testcases := map[string]string{
+		"roster.json": `{"teams": {}, "team": {}}`,
+		"roster.yaml": "teams: {}\nteam: {}\n",
+	}
+	for name, content := range testcases {
+		filePath := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		f := rosterFile{}
+		if err := decodeConfigFile(filePath, &f); err == nil || !strings.Contains(err.Error(), "team") {
+			t.Errorf("%s : expected an error for the unknown field got %v", name, err)
+		}
+	}
+}
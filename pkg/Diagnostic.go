@@ -0,0 +1,73 @@
+package codeowners
+
+import (
+	"fmt"
+)
+
+// Severity of a Diagnostic
+type Severity int
+
+const (
+	SeverityError   Severity = iota // The line could not be used
+	SeverityWarning                 // The line was used but is likely a mistake
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// DiagnosticCode identifies the kind of problem a Diagnostic reports, codes
+// are stable and safe to match on
+type DiagnosticCode string
+
+const (
+	CodeMissingPath  DiagnosticCode = "CO001" // Line holds owners but no path
+	CodeInvalidOwner DiagnosticCode = "CO002" // Owner is neither a @name nor an email, or not supported by the dialect
+
+	CodeUnsupportedPattern DiagnosticCode = "CO003" // Pattern uses syntax the dialect ignores, the rule is skipped
+	CodeUndefinedGroup     DiagnosticCode = "CO004" // Bitbucket group is used before it is defined
+	CodeInvalidMergeCheck  DiagnosticCode = "CO005" // Bitbucket merge check is not of the form Check(@@Group >= n)
+	CodeUnknownUser        DiagnosticCode = "CO006" // User is not in the OwnerDirectory
+	CodeUnknownTeam        DiagnosticCode = "CO007" // Team is not in the OwnerDirectory
+	CodeUnverifiedEmail    DiagnosticCode = "CO008" // Email does not belong to a verified user of the OwnerDirectory
+)
+
+// Diagnostic is a problem found in a CODEOWNERS file along with the exact
+// position of the offending token
+type Diagnostic struct {
+	File     string // fragment file of the token, empty when a single file was parsed
+	Line     int    // 1 based line number
+	Column   int    // 1 based column, counted in characters
+	Start    int    // byte offset of the start of the token in the file
+	End      int    // byte offset just past the end of the token in the file
+	Severity Severity
+	Code     DiagnosticCode
+	Message  string
+}
+
+// newDiagnostic creates an error diagnostic for the token lit found at pos
+func newDiagnostic(code DiagnosticCode, pos Position, lit string, format string, a ...interface{}) *Diagnostic {
+	return &Diagnostic{
+		Line:     pos.Line,
+		Column:   pos.Column,
+		Start:    pos.Offset,
+		End:      pos.Offset + len(lit),
+		Severity: SeverityError,
+		Code:     code,
+		Message:  fmt.Sprintf(format, a...),
+	}
+}
+
+func (d *Diagnostic) Error() string {
+	kind := "Syntax Error"
+	if d.Severity == SeverityWarning {
+		kind = "Warning"
+	}
+	if d.File != "" {
+		return fmt.Sprintf("%s: %s On Line %d: %s", d.File, kind, d.Line, d.Message)
+	}
+	return fmt.Sprintf("%s On Line %d: %s", kind, d.Line, d.Message)
+}
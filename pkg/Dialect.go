@@ -0,0 +1,206 @@
+package codeowners
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Dialect is the flavour of CODEOWNERS understood by a code hosting platform.
+// It decides which syntax is parsed, which owners are valid, how patterns
+// match paths and how the owners of matching rules are combined.
+type Dialect interface {
+	// Name of the platform
+	Name() string
+	// Locations returns the paths, relative to the repository root, where the
+	// platform looks for the CODEOWNERS file in order of precedence
+	Locations() []string
+	// Sections reports whether GitLab style [Section] headers are supported
+	Sections() bool
+	// ClassifyOwner returns the kind of an owner, or an error when the
+	// platform does not accept it
+	ClassifyOwner(owner string) (OwnerKind, error)
+	// CheckPattern returns an error when the platform ignores rules using the
+	// pattern, such rules are reported as warnings and left out of the index
+	CheckPattern(pattern string) error
+	// CompilePattern compiles a pattern as written in the CODEOWNERS file
+	CompilePattern(pattern string, foldCase bool) Matcher
+	// Resolution returns how the owners of matching rules are combined
+	Resolution() Resolution
+}
+
+// Matcher matches paths against a compiled CODEOWNERS pattern
+type Matcher interface {
+	// Match reports whether the pattern owns the file or directory at path,
+	// directories end with a slash
+	Match(path string) bool
+	// MatchesBelow reports whether the pattern may own paths inside dir
+	MatchesBelow(dir string) bool
+}
+
+var (
+	// Generic accepts the syntax of every platform and is used when no
+	// dialect is given
+	Generic Dialect = genericDialect{}
+	// GitHub ignores rules using negation, character ranges or an escaped #,
+	// and only accepts users, teams and emails as owners
+	GitHub Dialect = githubDialect{}
+	// GitLab supports sections, nested groups and roles
+	GitLab Dialect = gitlabDialect{}
+	// Bitbucket follows the Code Owners app for Bitbucket, which declares
+	// owner groups and merge checks in the file
+	Bitbucket Dialect = bitbucketDialect{}
+	// Gitea patterns are regular expressions, a leading ! negates them, and
+	// every matching rule contributes its owners
+	Gitea Dialect = giteaDialect{}
+)
+
+type genericDialect struct{}
+
+func (genericDialect) Name() string { return "generic" }
+
+func (genericDialect) Locations() []string {
+	return []string{"CODEOWNERS", ".github/CODEOWNERS", ".gitlab/CODEOWNERS", "docs/CODEOWNERS"}
+}
+
+func (genericDialect) Sections() bool { return true }
+
+func (genericDialect) ClassifyOwner(owner string) (OwnerKind, error) { return ClassifyOwner(owner) }
+
+func (genericDialect) CheckPattern(pattern string) error { return nil }
+
+func (genericDialect) CompilePattern(pattern string, foldCase bool) Matcher {
+	return compilePattern(pattern, foldCase)
+}
+
+func (genericDialect) Resolution() Resolution { return LastMatch }
+
+type githubDialect struct{ genericDialect }
+
+func (githubDialect) Name() string { return "github" }
+
+func (githubDialect) Locations() []string {
+	return []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+}
+
+func (githubDialect) Sections() bool { return false }
+
+func (githubDialect) ClassifyOwner(owner string) (OwnerKind, error) {
+	return restrictOwner(owner, "GitHub", UserOwner, GroupOwner, EmailOwner)
+}
+
+func (githubDialect) CheckPattern(pattern string) error {
+	switch {
+	case strings.HasPrefix(pattern, "!"):
+		return fmt.Errorf("GitHub does not support negated patterns, the rule for %s is ignored", pattern)
+	case strings.HasPrefix(pattern, "\\#"):
+		return fmt.Errorf("GitHub does not support escaping a leading #, the rule for %s is ignored", pattern)
+	case strings.ContainsAny(pattern, "[]"):
+		return fmt.Errorf("GitHub does not support character ranges, the rule for %s is ignored", pattern)
+	}
+	return nil
+}
+
+type gitlabDialect struct{ genericDialect }
+
+func (gitlabDialect) Name() string { return "gitlab" }
+
+func (gitlabDialect) Locations() []string {
+	return []string{"CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+}
+
+type bitbucketDialect struct{ genericDialect }
+
+func (bitbucketDialect) Name() string { return "bitbucket" }
+
+func (bitbucketDialect) Locations() []string {
+	return []string{"CODEOWNERS", ".bitbucket/CODEOWNERS", "docs/CODEOWNERS"}
+}
+
+func (bitbucketDialect) Sections() bool { return false }
+
+func (bitbucketDialect) ClassifyOwner(owner string) (OwnerKind, error) {
+	if strings.HasPrefix(owner, "@@") {
+		if !rxName.MatchString(owner[2:]) {
+			return 0, fmt.Errorf("%s is not a valid group name", owner)
+		}
+		return GroupOwner, nil
+	}
+	return restrictOwner(owner, "Bitbucket", UserOwner, EmailOwner)
+}
+
+type giteaDialect struct{ genericDialect }
+
+func (giteaDialect) Name() string { return "gitea" }
+
+func (giteaDialect) Locations() []string {
+	return []string{"CODEOWNERS", "docs/CODEOWNERS", ".gitea/CODEOWNERS"}
+}
+
+func (giteaDialect) Sections() bool { return false }
+
+func (giteaDialect) ClassifyOwner(owner string) (OwnerKind, error) {
+	return restrictOwner(owner, "Gitea", UserOwner, GroupOwner)
+}
+
+func (giteaDialect) CheckPattern(pattern string) error {
+	_, err := regexp.Compile(strings.TrimPrefix(pattern, "!"))
+	return err
+}
+
+func (giteaDialect) CompilePattern(pattern string, foldCase bool) Matcher {
+	return compileRegexpPattern(pattern, foldCase)
+}
+
+func (giteaDialect) Resolution() Resolution { return Union }
+
+// restrictOwner classifies an owner and rejects the kinds the platform does not support
+func restrictOwner(owner string, platform string, kinds ...OwnerKind) (OwnerKind, error) {
+	kind, err := ClassifyOwner(owner)
+	if err != nil {
+		return 0, err
+	}
+	for _, k := range kinds {
+		if k == kind {
+			return kind, nil
+		}
+	}
+	return 0, fmt.Errorf("%s does not support %s owners such as %s", platform, kind, owner)
+}
+
+// regexpPattern is a Gitea pattern, a regular expression matched against the
+// whole path. A leading ! makes the rule own every path the expression does
+// not match.
+type regexpPattern struct {
+	re     *regexp.Regexp
+	prefix string // literal text every matching path starts with
+	negate bool
+}
+
+func compileRegexpPattern(raw string, foldCase bool) *regexpPattern {
+	p := &regexpPattern{negate: strings.HasPrefix(raw, "!")}
+	expr := strings.TrimPrefix(strings.TrimPrefix(raw, "!"), "/")
+	if foldCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		// Patterns are checked while parsing, an invalid one never matches
+		re = regexp.MustCompile(`$.^`)
+	}
+	p.prefix, _ = re.LiteralPrefix()
+	p.re = regexp.MustCompile("^(?:" + re.String() + ")$")
+	return p
+}
+
+func (p *regexpPattern) Match(filePath string) bool {
+	return p.re.MatchString(strings.Trim(filePath, "/")) != p.negate
+}
+
+func (p *regexpPattern) MatchesBelow(dir string) bool {
+	if p.negate {
+		return true
+	}
+	dir = strings.Trim(dir, "/") + "/"
+	return dir == "/" || strings.HasPrefix(p.prefix, dir) || strings.HasPrefix(dir, p.prefix)
+}
@@ -0,0 +1,243 @@
+package codeowners
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGitHubDialect(t *testing.T) {
+	input := `* @global
+[Mm]akefile @build
+!vendor/ @nobody
+\#notes @notes
+[Docs] @docs
+docs/ @org/docs
+`
+	co, errs := BuildIndex([]byte(input), WithDialect(GitHub))
+	if co == nil {
+		t.Fatalf("expected an index, got %v", errs)
+	}
+	if len(errs) != 4 {
+		t.Fatalf("expected 4 warnings got %v", errs)
+	}
+	for i, line := range []int{2, 3, 4, 5} {
+		d := errs[i].(*Diagnostic)
+		if d.Line != line || d.Code != CodeUnsupportedPattern || d.Severity != SeverityWarning {
+			t.Errorf("unexpected diagnostic %+v", d)
+		}
+	}
+	if len(co.Sections()) != 0 {
+		t.Errorf("GitHub has no sections, got %v", co.Sections())
+	}
+
+	testcases := map[string][]string{
+		"Makefile":      {"@global"},
+		"vendor/lib.go": {"@global"},
+		"docs/index.md": {"@org/docs"},
+	}
+	for path, expected := range testcases {
+		if owners := co.FindOwners(path); !reflect.DeepEqual(owners, expected) {
+			t.Errorf("%s : expected %v got %v", path, expected, owners)
+		}
+	}
+
+	// The file is saved as written, ignored rules included
+	if co.doc.String() != input {
+		t.Errorf("expected the document to be unchanged, got %q", co.doc.String())
+	}
+}
+
+func TestDialectOwners(t *testing.T) {
+	testcases := []struct {
+		dialect Dialect
+		owner   string
+		valid   bool
+	}{
+		{dialect: GitHub, owner: "@org/team", valid: true},
+		{dialect: GitHub, owner: "jane@example.com", valid: true},
+		{dialect: GitHub, owner: "@group/nested/team", valid: false},
+		{dialect: GitHub, owner: "@@maintainer", valid: false},
+		{dialect: GitLab, owner: "@group/nested/team", valid: true},
+		{dialect: GitLab, owner: "@@maintainer", valid: true},
+		{dialect: Gitea, owner: "@org/team", valid: true},
+		{dialect: Gitea, owner: "jane@example.com", valid: false},
+	}
+
+	for _, tc := range testcases {
+		_, errs := BuildIndex([]byte("docs/ "+tc.owner), WithDialect(tc.dialect))
+		if (len(errs) == 0) != tc.valid {
+			t.Errorf("%s %s : expected valid to be %v got %v", tc.dialect.Name(), tc.owner, tc.valid, errs)
+			continue
+		}
+		if !tc.valid && errs[0].(*Diagnostic).Code != CodeInvalidOwner {
+			t.Errorf("%s %s : expected an invalid owner got %v", tc.dialect.Name(), tc.owner, errs[0])
+		}
+	}
+}
+
+func TestGitLabDialectSections(t *testing.T) {
+	co, errs := BuildIndex([]byte("[Docs] @docs\n*.md\n"), WithDialect(GitLab))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if owners := co.FindOwners("README.md"); !reflect.DeepEqual(owners, []string{"@docs"}) {
+		t.Errorf("expected the section default owners got %v", owners)
+	}
+}
+
+func TestGiteaDialect(t *testing.T) {
+	input := `.*\.go @gophers
+docs/.* @docs
+!.*\.md @not-docs
+[ @broken
+`
+	co, errs := BuildIndex([]byte(input), WithDialect(Gitea))
+	if co == nil {
+		t.Fatalf("expected an index, got %v", errs)
+	}
+	if len(errs) != 1 || errs[0].(*Diagnostic).Line != 4 {
+		t.Fatalf("expected a warning for the invalid expression got %v", errs)
+	}
+
+	testcases := map[string][]string{
+		"main.go":         {"@not-docs", "@gophers"},
+		"docs/example.go": {"@not-docs", "@docs", "@gophers"},
+		"docs/index.md":   {"@docs"},
+		"README.md":       {},
+		"main.go.bak":     {"@not-docs"},
+	}
+	for path, expected := range testcases {
+		if owners := co.FindOwners(path); !reflect.DeepEqual(owners, expected) {
+			t.Errorf("%s : expected %v got %v", path, expected, owners)
+		}
+	}
+
+	dir := co.FindDirectoryOwners("docs")
+	if len(dir.Subtree) != 3 {
+		t.Errorf("expected every rule to match below docs got %v", dir.Subtree)
+	}
+	dir = co.FindDirectoryOwners("src")
+	if len(dir.Subtree) != 2 {
+		t.Errorf("expected the docs rule to be left out got %v", dir.Subtree)
+	}
+}
+
+func TestBuildFromRepository(t *testing.T) {
+	root, err := ioutil.TempDir("", "codeowners")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	write := func(location, content string) {
+		filePath := filepath.Join(root, filepath.FromSlash(location))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("CODEOWNERS", "main.go @root\n")
+	write(".github/CODEOWNERS", "* @github\n")
+
+	testcases := []struct {
+		dialect Dialect
+		owner   string
+	}{
+		{dialect: GitHub, owner: "@github"},
+		{dialect: GitLab, owner: "@root"},
+		{dialect: Gitea, owner: "@root"},
+	}
+	for _, tc := range testcases {
+		co, errs := BuildFromRepository(root, WithDialect(tc.dialect))
+		if len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		if owners := co.FindOwners("main.go"); !reflect.DeepEqual(owners, []string{tc.owner}) {
+			t.Errorf("%s : expected %s got %v", tc.dialect.Name(), tc.owner, owners)
+		}
+	}
+
+	if _, errs := BuildFromRepository(filepath.Join(root, "missing")); len(errs) == 0 {
+		t.Error("expected an error for a repository without a CODEOWNERS file")
+	}
+}
+
+func TestBitbucketDialect(t *testing.T) {
+	co, errs := BuildFromFile("fixtures/testCODEOWNERS_Bitbucket", WithDialect(Bitbucket))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	groups := co.Groups()
+	if len(groups) != 2 || groups[1].Owner() != "@@Backend" || groups[1].Line() != 3 {
+		t.Fatalf("unexpected groups %v", groups)
+	}
+	if !reflect.DeepEqual(groups[1].Members, []string{"@ann", "@bob", "carl@example.com"}) {
+		t.Errorf("unexpected members %v", groups[1].Members)
+	}
+
+	owners := co.FindOwners("api/docs/index.md")
+	if !reflect.DeepEqual(owners, []string{"@@Frontend", "@@Backend"}) {
+		t.Errorf("expected the groups as written got %v", owners)
+	}
+	expanded := co.ExpandOwners(append(owners, "@jane", "@lead"))
+	if !reflect.DeepEqual(expanded, []string{"@jane", "@john", "@ann", "@bob", "carl@example.com", "@lead"}) {
+		t.Errorf("unexpected expansion %v", expanded)
+	}
+
+	checks := co.MergeChecks()
+	if len(checks) != 2 || checks[1].Group != "@@Backend" || checks[1].Approvals != 2 || checks[1].Line() != 11 {
+		t.Fatalf("unexpected merge checks %v", checks)
+	}
+
+	testcases := []struct {
+		changed   []string
+		approvers []string
+		required  []bool
+		passed    []bool
+	}{
+		{changed: []string{"README.md"}, approvers: []string{}, required: []bool{false, false}, passed: []bool{true, true}},
+		{changed: []string{"app.js"}, approvers: []string{"@ann"}, required: []bool{true, false}, passed: []bool{false, true}},
+		{changed: []string{"app.js", "api/handler.go"}, approvers: []string{"@john", "@ann"}, required: []bool{true, true}, passed: []bool{true, false}},
+		{changed: []string{"api/handler.go"}, approvers: []string{"@ann", "carl@example.com"}, required: []bool{false, true}, passed: []bool{true, true}},
+	}
+	for _, tc := range testcases {
+		results := co.EvaluateMergeChecks(tc.changed, tc.approvers)
+		for i, r := range results {
+			if r.Required != tc.required[i] || r.Passed != tc.passed[i] {
+				t.Errorf("%v approved by %v : %s expected required %v passed %v got %+v", tc.changed, tc.approvers, r.Check.Group, tc.required[i], tc.passed[i], r)
+			}
+		}
+	}
+
+	// Groups and checks are kept when the file is saved
+	input, _ := ioutil.ReadFile("fixtures/testCODEOWNERS_Bitbucket")
+	if co.doc.String() != string(input) {
+		t.Errorf("expected the document to be unchanged, got %q", co.doc.String())
+	}
+}
+
+func TestBitbucketDialectErrors(t *testing.T) {
+	testcases := []struct {
+		input string
+		code  DiagnosticCode
+	}{
+		{input: "*.js @@Frontend", code: CodeUndefinedGroup},
+		{input: "Check(@@Frontend >= 1)", code: CodeUndefinedGroup},
+		{input: "@@@Frontend @jane\nCheck(@@Frontend > 1)", code: CodeInvalidMergeCheck},
+		{input: "@@@Frontend @@Backend", code: CodeInvalidOwner},
+		{input: "*.js @org/team", code: CodeInvalidOwner},
+	}
+
+	for _, tc := range testcases {
+		_, errs := BuildIndex([]byte(tc.input), WithDialect(Bitbucket))
+		if len(errs) != 1 || errs[0].(*Diagnostic).Code != tc.code {
+			t.Errorf("%q : expected a %s diagnostic got %v", tc.input, tc.code, errs)
+		}
+	}
+}
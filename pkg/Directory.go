@@ -0,0 +1,177 @@
+package codeowners
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OwnerDirectory knows which users, teams and emails exist on the platform
+// hosting the repository, so owners which are well formed but do not exist,
+// such as a misspelt team, are reported
+type OwnerDirectory interface {
+	// UserExists reports whether there is a user with the name, without its @
+	UserExists(name string) (bool, error)
+	// TeamExists reports whether the organization has a team with the name
+	TeamExists(org, team string) (bool, error)
+	// EmailVerified reports whether the email belongs to a verified user
+	EmailVerified(email string) (bool, error)
+}
+
+// ValidateOwners looks up every user, team and email owning rules or sections
+// in the directory, aliased owners are looked up as the owner they resolve
+// to. Owners which do not exist are reported as warnings at the owner as
+// written, an error from the directory ends validation and is returned last.
+func (t *CodeOwners) ValidateOwners(d OwnerDirectory) []error {
+	errors := []error{}
+	known := map[string]*Diagnostic{} // diagnostic for every owner looked up, nil when it exists
+	offset := 0
+	if t.document().bom {
+		offset = len(bom)
+	}
+
+	for i, l := range t.document().lines {
+		text := l.String()
+		owners := []string{}
+		if l.entry != nil {
+			owners = l.entry.owners
+		} else if l.section != nil {
+			owners = l.section.DefaultOwners
+		}
+
+		for _, raw := range owners {
+			// Aliased owners are looked up as the owner they resolve to
+			resolved := raw
+			if t.aliases != nil {
+				resolved = t.aliases.Resolve(raw)
+			}
+			result, ok := known[resolved]
+			if !ok {
+				var err error
+				result, err = t.lookupOwner(d, t.owner(resolved))
+				if err != nil {
+					return append(errors, err)
+				}
+				known[resolved] = result
+			}
+			if result != nil {
+				diagnostic := *result
+				if resolved != raw {
+					diagnostic.Message += fmt.Sprintf(", %s resolves to it through the aliases", raw)
+				}
+				pos := ownerPosition(text, raw)
+				diagnostic.Line = i + 1
+				diagnostic.Column = pos.Column
+				diagnostic.Start = offset + pos.Offset
+				diagnostic.End = diagnostic.Start + len(raw)
+				errors = append(errors, &diagnostic)
+			}
+		}
+		offset += len(text) + len(l.eol)
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// lookupOwner returns a diagnostic when the owner does not exist in the
+// directory, nil when it does or cannot be looked up
+func (t *CodeOwners) lookupOwner(d OwnerDirectory, o Owner) (*Diagnostic, error) {
+	var exists bool
+	var err error
+	var code DiagnosticCode
+	var message string
+	switch {
+	case o.Kind == UserOwner:
+		exists, err = d.UserExists(o.Name)
+		code, message = CodeUnknownUser, "%s is not a known user"
+	case (o.Kind == GroupOwner || o.Kind == NestedGroupOwner) && o.Org != "":
+		exists, err = d.TeamExists(o.Org, o.Name)
+		code, message = CodeUnknownTeam, "%s is not a known team"
+	case o.Kind == EmailOwner:
+		exists, err = d.EmailVerified(o.Name)
+		code, message = CodeUnverifiedEmail, "%s does not belong to a verified user"
+	default:
+		// Roles, groups defined in the file and unknown owners are not looked up
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot look up %s: %v", o.Raw, err)
+	}
+	if exists {
+		return nil, nil
+	}
+	diagnostic := newDiagnostic(code, Position{}, o.Raw, message, o.Raw)
+	diagnostic.Severity = SeverityWarning
+	return diagnostic, nil
+}
+
+// ownerPosition returns the position of an owner within a line, skipping the
+// path or section header, and the start of the line when the line does not
+// hold the owner as written
+func ownerPosition(text string, owner string) Position {
+	s := NewScanner(strings.NewReader(text))
+	words := 0
+	for {
+		tok, lit := s.Scan()
+		if tok == EOF {
+			return Position{Column: 1}
+		}
+		if tok != IDENT {
+			continue
+		}
+		if words++; words > 1 && lit == owner {
+			return s.Position()
+		}
+	}
+}
+
+// FileDirectory is an OwnerDirectory read from a YAML or JSON file, for
+// validating owners offline, e.g
+//
+//	users: [jane, john]
+//	teams: [org/payments, org/platform]
+//	emails: [jane@example.com]
+//
+// Names are matched regardless of case.
+type FileDirectory struct {
+	users  map[string]bool
+	teams  map[string]bool
+	emails map[string]bool
+}
+
+type directoryFile struct {
+	Users  []string `yaml:"users" json:"users"`
+	Teams  []string `yaml:"teams" json:"teams"`
+	Emails []string `yaml:"emails" json:"emails"`
+}
+
+// LoadFileDirectory reads a directory from a JSON or YAML file
+func LoadFileDirectory(filePath string) (*FileDirectory, error) {
+	f := directoryFile{}
+	if err := decodeConfigFile(filePath, &f); err != nil {
+		return nil, err
+	}
+
+	set := func(names []string) map[string]bool {
+		m := map[string]bool{}
+		for _, name := range names {
+			m[strings.ToLower(strings.TrimPrefix(name, "@"))] = true
+		}
+		return m
+	}
+	return &FileDirectory{users: set(f.Users), teams: set(f.Teams), emails: set(f.Emails)}, nil
+}
+
+func (d *FileDirectory) UserExists(name string) (bool, error) {
+	return d.users[strings.ToLower(name)], nil
+}
+
+func (d *FileDirectory) TeamExists(org, team string) (bool, error) {
+	return d.teams[strings.ToLower(org+"/"+team)], nil
+}
+
+func (d *FileDirectory) EmailVerified(email string) (bool, error) {
+	return d.emails[strings.ToLower(email)], nil
+}
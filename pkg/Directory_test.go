@@ -0,0 +1,123 @@
+package codeowners
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const directoryCodeOwners = `* @org/platform
+payments/ @org/paymnets @Jane
+docs/ @jnae docs@example.com jane@example.com
+[Database] @org/dba
+db/ @@maintainer
+`
+
+// diagnosticSummary formats diagnostics as "line:column code message"
+func diagnosticSummary(errs []error) []string {
+	summary := []string{}
+	for _, err := range errs {
+		if d, ok := err.(*Diagnostic); ok {
+			summary = append(summary, fmt.Sprintf("%d:%d %s %s %s", d.Line, d.Column, d.Severity, d.Code, d.Message))
+			continue
+		}
+		summary = append(summary, err.Error())
+	}
+	return summary
+}
+
+var expectedDirectoryDiagnostics = []string{
+	"2:11 warning CO007 @org/paymnets is not a known team",
+	"3:7 warning CO006 @jnae is not a known user",
+	"3:13 warning CO008 docs@example.com does not belong to a verified user",
+	"4:12 warning CO007 @org/dba is not a known team",
+}
+
+func TestFileDirectory(t *testing.T) {
+	d, err := LoadFileDirectory("fixtures/directory/directory.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	co, errs := BuildIndex([]byte(directoryCodeOwners), WithOwnerDirectory(d))
+	if co == nil {
+		t.Fatalf("expected the index to be built with warnings got %v", errs)
+	}
+	if summary := diagnosticSummary(errs); !reflect.DeepEqual(summary, expectedDirectoryDiagnostics) {
+		t.Errorf("expected %v got %v", expectedDirectoryDiagnostics, summary)
+	}
+
+	diagnostic := errs[0].(*Diagnostic)
+	if directoryCodeOwners[diagnostic.Start:diagnostic.End] != "@org/paymnets" {
+		t.Errorf("unexpected span %d-%d", diagnostic.Start, diagnostic.End)
+	}
+	if !strings.HasPrefix(diagnostic.Error(), "Warning On Line 2: ") {
+		t.Errorf("expected the diagnostic to read as a warning got %q", diagnostic.Error())
+	}
+}
+
+func TestFileDirectoryWithAliases(t *testing.T) {
+	d, err := LoadFileDirectory("fixtures/directory/directory.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewAliases(map[string]string{"@jane-old": "@jane", "@jdoe": "@ghost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := "* @jane-old @jdoe\n"
+	_, errs := BuildIndex([]byte(input), WithAliases(a), WithOwnerDirectory(d))
+	expected := []string{"1:13 warning CO006 @ghost is not a known user, @jdoe resolves to it through the aliases"}
+	if summary := diagnosticSummary(errs); !reflect.DeepEqual(summary, expected) {
+		t.Fatalf("expected %v got %v", expected, summary)
+	}
+	if diagnostic := errs[0].(*Diagnostic); input[diagnostic.Start:diagnostic.End] != "@jdoe" {
+		t.Errorf("expected the owner as written to be reported got %d-%d", diagnostic.Start, diagnostic.End)
+	}
+}
+
+func TestGitHubDirectory(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/users/Jane", "/orgs/org/teams/platform":
+			fmt.Fprint(w, `{}`)
+		case "/search/users":
+			count := 0
+			if r.URL.Query().Get("q") == "jane@example.com in:email" {
+				count = 1
+			}
+			fmt.Fprintf(w, `{"total_count": %d}`, count)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	d := NewGitHubDirectory("secret")
+	d.BaseURL = server.URL
+
+	_, errs := BuildIndex([]byte(directoryCodeOwners), WithOwnerDirectory(d))
+	if summary := diagnosticSummary(errs); !reflect.DeepEqual(summary, expectedDirectoryDiagnostics) {
+		t.Errorf("expected %v got %v", expectedDirectoryDiagnostics, summary)
+	}
+	if requests != 7 {
+		t.Errorf("expected every owner to be looked up once got %d requests", requests)
+	}
+
+	// API failures end validation instead of being reported as unknown owners
+	d.Token = "expired"
+	co, errs := BuildIndex([]byte(directoryCodeOwners), WithOwnerDirectory(d))
+	if co != nil || len(errs) != 1 || !strings.Contains(errs[0].Error(), "cannot look up @org/platform: GitHub API returned 401") {
+		t.Errorf("expected the API error got %v", errs)
+	}
+}
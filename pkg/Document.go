@@ -0,0 +1,215 @@
+package codeowners
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Document is a lossless concrete syntax tree of a CODEOWNERS file. It keeps
+// every line in its original order with its spacing, comments, blank lines
+// and line endings, so writing an unedited Document gives back the exact
+// bytes it was parsed from. Edited entries only rewrite their own line.
+type Document struct {
+	bom   bool
+	lines []*docLine
+}
+
+// docLine is a single line of a Document
+type docLine struct {
+	number   int      // line number in the parsed file, 0 for added lines
+	text     string   // line as written, without its line ending
+	eol      string   // "\n", "\r\n" or "" for a final line without a line ending
+	entry    *Entry   // rule or comment of the line, nil for blank and invalid lines
+	section  *Section // section declared by the line
+	group    *GroupDefinition
+	check    *MergeCheck
+	rendered string // entry or section as formatted when the line was parsed
+}
+
+func newDocument() *Document {
+	return &Document{lines: []*docLine{}}
+}
+
+// newDocumentFromEntries lays out entries one per line
+func newDocumentFromEntries(entries []*Entry) *Document {
+	doc := newDocument()
+	var section *Section
+	for _, entry := range entries {
+		if entry.section != section && entry.section != nil {
+			doc.lines = append(doc.lines, &docLine{section: entry.section, eol: "\n"})
+		}
+		section = entry.section
+		doc.append(entry)
+	}
+	return doc
+}
+
+// Entries returns the rules and comments of the document in file order
+func (d *Document) Entries() []*Entry {
+	entries := []*Entry{}
+	for _, l := range d.lines {
+		if l.entry != nil {
+			entries = append(entries, l.entry)
+		}
+	}
+	return entries
+}
+
+// Sections returns the GitLab sections declared in the document in file order
+func (d *Document) Sections() []*Section {
+	sections := []*Section{}
+	for _, l := range d.lines {
+		if l.section != nil {
+			sections = append(sections, l.section)
+		}
+	}
+	return sections
+}
+
+// Groups returns the Bitbucket owner groups declared in the document in file order
+func (d *Document) Groups() []*GroupDefinition {
+	groups := []*GroupDefinition{}
+	for _, l := range d.lines {
+		if l.group != nil {
+			groups = append(groups, l.group)
+		}
+	}
+	return groups
+}
+
+// MergeChecks returns the Bitbucket merge checks declared in the document in file order
+func (d *Document) MergeChecks() []*MergeCheck {
+	checks := []*MergeCheck{}
+	for _, l := range d.lines {
+		if l.check != nil {
+			checks = append(checks, l.check)
+		}
+	}
+	return checks
+}
+
+// lastSection returns the section a line appended to the document belongs to
+func (d *Document) lastSection() *Section {
+	for i := len(d.lines) - 1; i >= 0; i-- {
+		if d.lines[i].section != nil {
+			return d.lines[i].section
+		}
+	}
+	return nil
+}
+
+// Bytes returns the document as it is written to a CODEOWNERS file
+func (d *Document) Bytes() []byte {
+	var b bytes.Buffer
+	d.writeTo(&b)
+	return b.Bytes()
+}
+
+func (d *Document) String() string {
+	return string(d.Bytes())
+}
+
+func (d *Document) writeTo(b *bytes.Buffer) {
+	if d.bom {
+		b.WriteString(bom)
+	}
+	for _, l := range d.lines {
+		b.WriteString(l.String())
+		b.WriteString(l.eol)
+	}
+}
+
+// snapshot records the formatting of every entry so later edits can be detected
+func (d *Document) snapshot() {
+	for _, l := range d.lines {
+		if l.entry != nil {
+			l.rendered = formatEntry(l.entry)
+		} else if l.section != nil {
+			l.rendered = formatSection(l.section)
+		}
+	}
+}
+
+// append adds a line holding the entry at the end of the document
+func (d *Document) append(entry *Entry) {
+	eol := "\n"
+	if len(d.lines) > 0 {
+		last := d.lines[len(d.lines)-1]
+		eol = d.lineEnding()
+		if last.eol == "" {
+			last.eol = eol
+			eol = ""
+		}
+	}
+	d.lines = append(d.lines, &docLine{entry: entry, eol: eol})
+}
+
+// remove deletes the lines holding any of the entries
+func (d *Document) remove(entries ...*Entry) {
+	removed := map[*Entry]bool{}
+	for _, entry := range entries {
+		removed[entry] = true
+	}
+
+	lines := []*docLine{}
+	for _, l := range d.lines {
+		if l.entry == nil || !removed[l.entry] {
+			lines = append(lines, l)
+		}
+	}
+	if len(lines) > 0 && len(d.lines) > 0 {
+		// Keep the trailing line ending style of the file
+		lines[len(lines)-1].eol = d.lines[len(d.lines)-1].eol
+	}
+	d.lines = lines
+}
+
+// lineEnding returns the line ending used by the document
+func (d *Document) lineEnding() string {
+	for _, l := range d.lines {
+		if l.eol != "" {
+			return l.eol
+		}
+	}
+	return "\n"
+}
+
+// String returns the line without its line ending. Lines whose entry or
+// section changed since they were parsed are formatted again, keeping their
+// indentation.
+func (l *docLine) String() string {
+	var formatted string
+	switch {
+	case l.section != nil:
+		formatted = formatSection(l.section)
+	case l.entry != nil:
+		formatted = formatEntry(l.entry)
+	default:
+		return l.text
+	}
+	if l.number > 0 && formatted == l.rendered {
+		return l.text
+	}
+	indent := l.text[:len(l.text)-len(strings.TrimLeft(l.text, " \t"))]
+	return indent + formatted
+}
+
+// formatEntry formats an entry as a CODEOWNERS line
+func formatEntry(e *Entry) string {
+	if e.suffix == PathSufix(None) {
+		return e.comment
+	}
+	line := strings.Join(append([]string{e.rawPath()}, e.owners...), " ")
+	if e.comment != "" {
+		line += " " + e.comment
+	}
+	return line
+}
+
+// formatSection formats a section header as a CODEOWNERS line
+func formatSection(s *Section) string {
+	if s.comment != "" {
+		return s.String() + " " + s.comment
+	}
+	return s.String()
+}
@@ -0,0 +1,123 @@
+package codeowners
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// Source is the fragment file and line a line of a compiled CODEOWNERS file
+// comes from
+type Source struct {
+	File string
+	Line int // 0 for the lines added by the compiler
+}
+
+func (s Source) String() string {
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
+}
+
+// Fragments is a CODEOWNERS file compiled from fragments, such as the files
+// matching .github/codeowners.d/*.codeowners. Fragments are concatenated in
+// the lexical order of their file names, each preceded by a comment naming
+// it, so the rules of later fragments take precedence. GitLab sections carry
+// on into the following fragments as they would in the compiled file.
+type Fragments struct {
+	Files   []string // fragment files in the order they were compiled
+	Content []byte   // compiled CODEOWNERS file
+	lines   []fragmentLine
+}
+
+// fragmentLine maps a line of the compiled file to its fragment
+type fragmentLine struct {
+	source Source
+	start  int // byte offset of the line in the compiled file
+	offset int // byte offset of the line in its fragment
+}
+
+// CompileFragments compiles the fragment files matching the glob pattern
+func CompileFragments(pattern string) (*Fragments, error) {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no CODEOWNERS fragments match %s", pattern)
+	}
+
+	f := &Fragments{Files: files, lines: []fragmentLine{}}
+	var b bytes.Buffer
+	for _, file := range files {
+		input, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		// Offsets in the fragment count the byte order mark left out of the compiled file
+		stripped := len(input)
+		input = bytes.TrimPrefix(input, []byte(bom))
+		stripped -= len(input)
+
+		f.lines = append(f.lines, fragmentLine{source: Source{File: file}, start: b.Len()})
+		fmt.Fprintf(&b, "# Source: %s\n", filepath.ToSlash(file))
+
+		offset := 0
+		for number := 1; offset < len(input); number++ {
+			end := bytes.IndexByte(input[offset:], '\n') + 1
+			if end == 0 {
+				end = len(input) - offset
+			}
+			f.lines = append(f.lines, fragmentLine{source: Source{File: file, Line: number}, start: b.Len(), offset: stripped + offset})
+			b.Write(input[offset : offset+end])
+			offset += end
+		}
+		if len(input) > 0 && input[len(input)-1] != '\n' {
+			b.WriteByte('\n')
+		}
+	}
+	f.Content = b.Bytes()
+	return f, nil
+}
+
+// Source returns the fragment a line of the compiled file comes from
+func (f *Fragments) Source(line int) Source {
+	if line < 1 || line > len(f.lines) {
+		return Source{}
+	}
+	return f.lines[line-1].source
+}
+
+// BuildIndex builds the index for the compiled file. Diagnostics, entries and
+// sections point at the fragment file and line they come from.
+func (f *Fragments) BuildIndex(opts ...Option) (*CodeOwners, []error) {
+	t, errors := BuildIndex(f.Content, opts...)
+	for _, err := range errors {
+		if d, ok := err.(*Diagnostic); ok {
+			f.mapDiagnostic(d)
+		}
+	}
+	if t == nil {
+		return nil, errors
+	}
+
+	for _, entry := range t.document().Entries() {
+		source := f.Source(entry.line)
+		entry.file, entry.line = source.File, source.Line
+	}
+	for _, section := range t.Sections() {
+		source := f.Source(section.line)
+		section.file, section.line = source.File, source.Line
+	}
+	return t, errors
+}
+
+// mapDiagnostic moves a diagnostic from the compiled file to its fragment
+func (f *Fragments) mapDiagnostic(d *Diagnostic) {
+	if d.Line < 1 || d.Line > len(f.lines) {
+		return
+	}
+	l := f.lines[d.Line-1]
+	d.File, d.Line = l.source.File, l.source.Line
+	d.Start += l.offset - l.start
+	d.End += l.offset - l.start
+}
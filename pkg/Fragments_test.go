@@ -0,0 +1,126 @@
+package codeowners
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCompileFragments(t *testing.T) {
+	f, err := CompileFragments("fixtures/codeowners.d/*.codeowners")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# Source: fixtures/codeowners.d/10-default.codeowners
+# Default owners
+* @default-owner
+# Source: fixtures/codeowners.d/20-web.codeowners
+*.js @web-team
+
+/web/vendor/
+*.css @web-team @designers
+# Source: fixtures/codeowners.d/30-docs.codeowners
+[Documentation] @docs-team
+docs/
+*.md @writers
+`
+	if string(f.Content) != expected {
+		t.Errorf("unexpected compiled file %q", f.Content)
+	}
+	if source := f.Source(8); source.String() != "fixtures/codeowners.d/20-web.codeowners:4" {
+		t.Errorf("unexpected source for line 8 %s", source)
+	}
+	if source := f.Source(4); source.Line != 0 {
+		t.Errorf("expected the fragment header to have no line got %s", source)
+	}
+
+	co, errs := f.BuildIndex()
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	testcases := []struct {
+		path  string
+		file  string
+		line  int
+		owner string
+	}{
+		{path: "main.go", file: "10-default.codeowners", line: 2, owner: "@default-owner"},
+		{path: "web/app.css", file: "20-web.codeowners", line: 4, owner: "@web-team"},
+		{path: "docs/index.md", file: "30-docs.codeowners", line: 3, owner: "@writers"},
+	}
+	for _, tc := range testcases {
+		m := co.Match(tc.path)
+		if m.Rule == nil || m.Rule.File() != filepath.Join("fixtures/codeowners.d", tc.file) || m.Rule.Line() != tc.line {
+			t.Errorf("%s : expected the rule at %s:%d got %+v", tc.path, tc.file, tc.line, m.Rule)
+			continue
+		}
+		if m.Owners[0] != tc.owner {
+			t.Errorf("%s : expected %s got %v", tc.path, tc.owner, m.Owners)
+		}
+	}
+
+	sections := co.Sections()
+	if len(sections) != 1 || sections[0].File() != "fixtures/codeowners.d/30-docs.codeowners" || sections[0].Line() != 1 {
+		t.Errorf("unexpected sections %+v", sections)
+	}
+}
+
+func TestCompileFragmentsDiagnostics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "codeowners.d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fragments := map[string]string{
+		"a.codeowners": "* @default\n",
+		"b.codeowners": "docs/ @docs\n  *.md @writers not-an-owner\n",
+	}
+	for name, content := range fragments {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := CompileFragments(filepath.Join(dir, "*.codeowners"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	co, errs := f.BuildIndex()
+	if co != nil || len(errs) != 1 {
+		t.Fatalf("expected a single diagnostic got %v", errs)
+	}
+	d := errs[0].(*Diagnostic)
+	expected := &Diagnostic{
+		File:     filepath.Join(dir, "b.codeowners"),
+		Line:     2,
+		Column:   17,
+		Start:    28,
+		End:      40,
+		Severity: SeverityError,
+		Code:     CodeInvalidOwner,
+		Message:  "(not-an-owner) is an invalid owner",
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("expected %+v got %+v", expected, d)
+	}
+
+	// Offsets count the byte order mark of the fragment
+	if err := ioutil.WriteFile(filepath.Join(dir, "b.codeowners"), []byte(bom+fragments["b.codeowners"]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if f, err = CompileFragments(filepath.Join(dir, "*.codeowners")); err != nil {
+		t.Fatal(err)
+	}
+	_, errs = f.BuildIndex()
+	if d := errs[0].(*Diagnostic); d.Line != 2 || d.Start != expected.Start+len(bom) || d.End != expected.End+len(bom) {
+		t.Errorf("expected the diagnostic to be moved by the byte order mark got %+v", d)
+	}
+
+	if _, err := CompileFragments(filepath.Join(dir, "*.missing")); err == nil {
+		t.Error("expected an error when no fragments match")
+	}
+}
@@ -0,0 +1,89 @@
+package codeowners
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GitHubDirectory is an OwnerDirectory backed by the GitHub REST API. Looking
+// up teams needs a token allowed to read the organization.
+type GitHubDirectory struct {
+	BaseURL string // API root, https://api.github.com unless set for GitHub Enterprise
+	Token   string
+	Client  *http.Client
+}
+
+// NewGitHubDirectory creates a directory for github.com authenticating with the token
+func NewGitHubDirectory(token string) *GitHubDirectory {
+	return &GitHubDirectory{
+		BaseURL: "https://api.github.com",
+		Token:   token,
+		Client:  http.DefaultClient,
+	}
+}
+
+// UserExists looks the user up with GET /users/{username}
+func (d *GitHubDirectory) UserExists(name string) (bool, error) {
+	return d.exists("/users/" + url.PathEscape(name))
+}
+
+// TeamExists looks the team up with GET /orgs/{org}/teams/{team_slug}
+func (d *GitHubDirectory) TeamExists(org, team string) (bool, error) {
+	return d.exists("/orgs/" + url.PathEscape(org) + "/teams/" + url.PathEscape(team))
+}
+
+// EmailVerified searches for a user with the email. GitHub only finds users
+// whose email is public, so a private email is reported as unverified.
+func (d *GitHubDirectory) EmailVerified(email string) (bool, error) {
+	res, err := d.get("/search/users?q=" + url.QueryEscape(email+" in:email"))
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("GitHub API returned %s", res.Status)
+	}
+
+	result := struct {
+		TotalCount int `json:"total_count"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return false, err
+	}
+	return result.TotalCount > 0, nil
+}
+
+// exists reports whether the API has a resource at the path
+func (d *GitHubDirectory) exists(path string) (bool, error) {
+	res, err := d.get(path)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("GitHub API returned %s", res.Status)
+}
+
+func (d *GitHubDirectory) get(path string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(d.BaseURL, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if d.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+d.Token)
+	}
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
@@ -0,0 +1,397 @@
+package codeowners
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// OwnersAliasesFileName is the name of the file at the root of a Kubernetes
+// style repository declaring aliases for lists of users
+const OwnersAliasesFileName = "OWNERS_ALIASES"
+
+// KubernetesOwners is the ownership of a tree described by Kubernetes style
+// YAML OWNERS files, as used by Prow. Users are GitHub logins, returned with
+// a leading @ and in lower case as logins are case-insensitive. Index
+// converts the files to rules of a CodeOwners index.
+type KubernetesOwners struct {
+	files   map[string]*KubernetesOwnersFile
+	aliases map[string][]string
+}
+
+// KubernetesOwnersFile is a YAML OWNERS file, e.g
+//
+//	approvers:
+//	  - jane
+//	  - sig-storage-leads # alias from OWNERS_ALIASES
+//	reviewers:
+//	  - john
+//	labels:
+//	  - sig/storage
+//	options:
+//	  no_parent_owners: true
+//
+// Instead of the top level lists a file may hold filters, giving the lists
+// for the files whose path, relative to the directory, matches a regexp.
+type KubernetesOwnersFile struct {
+	Dir            string              // directory of the file relative to the root, "." for the root
+	NoParentOwners bool                // owners of parent directories do not apply
+	Filters        []*KubernetesFilter // top level lists are a filter matching every file
+}
+
+// KubernetesFilter holds the owners of the files matching a regexp
+type KubernetesFilter struct {
+	Pattern           string // regexp, ".*" for the top level lists
+	Approvers         []string
+	Reviewers         []string
+	Labels            []string
+	EmeritusApprovers []string // former approvers, who no longer own the files
+	re                *regexp.Regexp
+}
+
+// KubernetesMatch is the ownership of a path
+type KubernetesMatch struct {
+	Path              string
+	Approvers         []string
+	Reviewers         []string
+	Labels            []string
+	EmeritusApprovers []string
+	Files             []string // OWNERS files which apply to the path, closest first
+}
+
+type kubernetesConfig struct {
+	Approvers         []string `yaml:"approvers"`
+	Reviewers         []string `yaml:"reviewers"`
+	Labels            []string `yaml:"labels"`
+	EmeritusApprovers []string `yaml:"emeritus_approvers"`
+}
+
+type kubernetesOwnersYAML struct {
+	kubernetesConfig `yaml:",inline"`
+	Options          struct {
+		NoParentOwners bool `yaml:"no_parent_owners"`
+	} `yaml:"options"`
+	Filters map[string]kubernetesConfig `yaml:"filters"`
+}
+
+type kubernetesAliasesYAML struct {
+	Aliases map[string][]string `yaml:"aliases"`
+}
+
+// BuildFromKubernetesTree walks the tree at root and reads every YAML OWNERS
+// file, resolving aliases from the OWNERS_ALIASES file at the root when there
+// is one. The returned errors name the file at fault.
+func BuildFromKubernetesTree(root string) (*KubernetesOwners, []error) {
+	o := &KubernetesOwners{
+		files:   map[string]*KubernetesOwnersFile{},
+		aliases: map[string][]string{},
+	}
+
+	input, err := ioutil.ReadFile(filepath.Join(root, OwnersAliasesFileName))
+	if err == nil {
+		aliases := kubernetesAliasesYAML{}
+		if err := yaml.Unmarshal(input, &aliases); err != nil {
+			return nil, []error{fmt.Errorf("%s: %v", OwnersAliasesFileName, err)}
+		}
+		for alias, users := range aliases.Aliases {
+			o.aliases[strings.ToLower(alias)] = users
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, []error{err}
+	}
+
+	errors := []error{}
+	walkErr := filepath.Walk(root, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if info.IsDir() || info.Name() != OwnersFileName {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, filepath.Dir(filePath))
+		if err != nil {
+			return err
+		}
+		input, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		dir := filepath.ToSlash(rel)
+		f, err := o.parse(dir, input)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("%s: %v", path.Join(dir, OwnersFileName), err))
+			return nil
+		}
+		o.files[dir] = f
+		return nil
+	})
+	if walkErr != nil {
+		errors = append(errors, walkErr)
+	}
+	if len(errors) > 0 {
+		return nil, errors
+	}
+	return o, nil
+}
+
+// parse parses the OWNERS file of a directory
+func (o *KubernetesOwners) parse(dir string, input []byte) (*KubernetesOwnersFile, error) {
+	// Fields Prow supports but the index does not use, such as
+	// required_reviewers, are ignored like Prow ignores unknown fields
+	parsed := kubernetesOwnersYAML{}
+	if err := yaml.Unmarshal(input, &parsed); err != nil {
+		return nil, err
+	}
+
+	top := parsed.kubernetesConfig
+	hasTop := len(top.Approvers)+len(top.Reviewers)+len(top.Labels)+len(top.EmeritusApprovers) > 0
+	if hasTop && len(parsed.Filters) > 0 {
+		return nil, fmt.Errorf("filters cannot be used along with top level approvers, reviewers or labels")
+	}
+	if hasTop {
+		parsed.Filters = map[string]kubernetesConfig{".*": top}
+	}
+
+	f := &KubernetesOwnersFile{
+		Dir:            dir,
+		NoParentOwners: parsed.Options.NoParentOwners,
+		Filters:        []*KubernetesFilter{},
+	}
+	for pattern, config := range parsed.Filters {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("(%s) is an invalid filter: %v", pattern, err)
+		}
+		f.Filters = append(f.Filters, &KubernetesFilter{
+			Pattern:           pattern,
+			Approvers:         o.expand(config.Approvers),
+			Reviewers:         o.expand(config.Reviewers),
+			Labels:            config.Labels,
+			EmeritusApprovers: o.expand(config.EmeritusApprovers),
+			re:                re,
+		})
+	}
+	// Filters are a YAML map, sort them so results are deterministic
+	sort.Slice(f.Filters, func(i, j int) bool {
+		return f.Filters[i].Pattern < f.Filters[j].Pattern
+	})
+	return f, nil
+}
+
+// expand replaces aliases with their users and formats logins as owners
+func (o *KubernetesOwners) expand(users []string) []string {
+	owners := []string{}
+	for _, user := range users {
+		members, ok := o.aliases[strings.ToLower(user)]
+		if !ok {
+			members = []string{user}
+		}
+		for _, member := range members {
+			owners = append(owners, "@"+strings.ToLower(strings.TrimPrefix(member, "@")))
+		}
+	}
+	return removeDuplicates(owners)
+}
+
+// File returns the OWNERS file of a directory, nil when it has none
+func (o *KubernetesOwners) File(dir string) *KubernetesOwnersFile {
+	return o.files[cleanDir(dir)]
+}
+
+// Find returns the approvers, reviewers and labels of a path. The OWNERS
+// files of the directory of the path and of its parents apply, up to the
+// first one setting no_parent_owners.
+func (o *KubernetesOwners) Find(filePath string) *KubernetesMatch {
+	m := &KubernetesMatch{
+		Path:              filePath,
+		Approvers:         []string{},
+		Reviewers:         []string{},
+		Labels:            []string{},
+		EmeritusApprovers: []string{},
+		Files:             []string{},
+	}
+	filePath = strings.Trim(filePath, "/")
+
+	for dir := cleanDir(path.Dir(filePath)); ; dir = path.Dir(dir) {
+		if f, ok := o.files[dir]; ok {
+			rel := filePath
+			if dir != "." {
+				rel = strings.TrimPrefix(filePath, dir+"/")
+			}
+			for _, filter := range f.Filters {
+				if filter.re.MatchString(rel) {
+					m.Approvers = append(m.Approvers, filter.Approvers...)
+					m.Reviewers = append(m.Reviewers, filter.Reviewers...)
+					m.Labels = append(m.Labels, filter.Labels...)
+					m.EmeritusApprovers = append(m.EmeritusApprovers, filter.EmeritusApprovers...)
+				}
+			}
+			m.Files = append(m.Files, path.Join(dir, OwnersFileName))
+			if f.NoParentOwners {
+				break
+			}
+		}
+		if dir == "." {
+			break
+		}
+	}
+
+	m.Approvers = removeDuplicates(m.Approvers)
+	m.Reviewers = removeDuplicates(m.Reviewers)
+	m.Labels = removeDuplicates(m.Labels)
+	m.EmeritusApprovers = removeDuplicates(m.EmeritusApprovers)
+	return m
+}
+
+// FindApprovers returns the users who may approve changes to a path
+func (o *KubernetesOwners) FindApprovers(filePath string) []string {
+	return o.Find(filePath).Approvers
+}
+
+// FindReviewers returns the users who review changes to a path
+func (o *KubernetesOwners) FindReviewers(filePath string) []string {
+	return o.Find(filePath).Reviewers
+}
+
+// Index converts the OWNERS files to an index, so they are queried like a
+// CODEOWNERS file. Every filter becomes a rule owned by its approvers, along
+// with the approvers the directory inherits, and keeping its reviewers. The
+// index combines the owners of every matching rule like Prow does, a
+// directory setting no_parent_owners starts with a rule without owners.
+// Filters are converted when a pattern matches the same files, i.e ".*",
+// "\.ext$", "^name$" and "^dir/", other filters are returned as errors.
+func (o *KubernetesOwners) Index(opts ...Option) (*CodeOwners, []error) {
+	dirs := []string{}
+	for dir := range o.files {
+		dirs = append(dirs, dir)
+	}
+	// Parents come before their children, whose rules without owners clear theirs
+	sort.Slice(dirs, func(i, j int) bool {
+		if depth(dirs[i]) != depth(dirs[j]) {
+			return depth(dirs[i]) < depth(dirs[j])
+		}
+		return dirs[i] < dirs[j]
+	})
+
+	errors := []error{}
+	entries := []*Entry{}
+	inherited := map[string][]string{} // approvers of every file of the directories
+	for _, dir := range dirs {
+		f := o.files[dir]
+		approvers := []string{}
+		for _, filter := range f.Filters {
+			if filter.Pattern == ".*" {
+				approvers = append(approvers, filter.Approvers...)
+			}
+		}
+		if f.NoParentOwners {
+			if dir != "." {
+				entries = append(entries, newOwnersEntry(dir+"/", true, []string{}))
+			}
+		} else {
+			approvers = append(approvers, o.parentApprovers(dir, inherited)...)
+		}
+		inherited[dir] = removeDuplicates(approvers)
+
+		for _, filter := range f.Filters {
+			entry, ok := filterEntry(dir, filter.Pattern)
+			if !ok {
+				errors = append(errors, fmt.Errorf("%s: filter (%s) has no equivalent CODEOWNERS pattern", path.Join(dir, OwnersFileName), filter.Pattern))
+				continue
+			}
+			entry.owners = removeDuplicates(append(append([]string{}, filter.Approvers...), inherited[dir]...))
+			if len(entry.owners) == 0 {
+				// Without approvers the rule would clear the ownership of the files
+				continue
+			}
+			entry.reviewers = filter.Reviewers
+			entries = append(entries, entry)
+		}
+	}
+	if len(errors) > 0 {
+		return nil, errors
+	}
+
+	index, err := createIndexFromEntries(entries, opts...)
+	if err != nil {
+		return nil, []error{err}
+	}
+	index.SetResolution(Union)
+	return index, nil
+}
+
+// parentApprovers returns the approvers of the closest parent directory with an OWNERS file
+func (o *KubernetesOwners) parentApprovers(dir string, inherited map[string][]string) []string {
+	for dir != "." {
+		dir = path.Dir(dir)
+		if approvers, ok := inherited[dir]; ok {
+			return approvers
+		}
+	}
+	return []string{}
+}
+
+var (
+	extensionFilter = regexp.MustCompile(`^\\\.([\w-]+)\$$`)
+	pathFilter      = regexp.MustCompile(`^\^((?:[\w-]|\\\.)+(?:/(?:[\w-]|\\\.)+)*)(\$|/)$`)
+)
+
+// filterEntry returns an entry without owners whose pattern matches the same
+// files of the directory as the filter, false when there is no such pattern
+func filterEntry(dir, filter string) (*Entry, bool) {
+	prefix := ""
+	if dir != "." {
+		prefix = dir + "/"
+	}
+	if filter == ".*" {
+		if dir == "." {
+			return newOwnersEntry("*", false, []string{}), true
+		}
+		return newOwnersEntry(prefix, true, []string{}), true
+	}
+	if m := extensionFilter.FindStringSubmatch(filter); m != nil {
+		if dir == "." {
+			return newOwnersEntry("*."+m[1], false, []string{}), true
+		}
+		return newOwnersEntry(prefix+"**/*."+m[1], true, []string{}), true
+	}
+	if m := pathFilter.FindStringSubmatch(filter); m != nil {
+		name := strings.Replace(m[1], "\\.", ".", -1)
+		if m[2] == "/" {
+			name += "/"
+		}
+		return newOwnersEntry(prefix+name, true, []string{}), true
+	}
+	return nil, false
+}
+
+// FindReviewers returns the reviewers kept by the rules giving ownership of
+// a path, for an index converted from Kubernetes OWNERS files
+func (t *CodeOwners) FindReviewers(path string) []string {
+	reviewers := []string{}
+	for _, en := range contributingEntries(t.matchingEntries(path), t.resolution) {
+		reviewers = append(reviewers, en.reviewers...)
+	}
+	return removeDuplicates(reviewers)
+}
+
+// cleanDir returns a directory relative to the root in the form used as key
+// of the OWNERS files, "." for the root
+func cleanDir(dir string) string {
+	dir = strings.Trim(dir, "/")
+	if dir == "" {
+		return "."
+	}
+	return path.Clean(dir)
+}
@@ -0,0 +1,161 @@
+package codeowners
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildFromKubernetesTree(t *testing.T) {
+	o, errs := BuildFromKubernetesTree("fixtures/kubernetes")
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	testcases := []struct {
+		path      string
+		approvers []string
+		reviewers []string
+		labels    []string
+		files     []string
+	}{
+		{
+			path:      "main.go",
+			approvers: []string{"@root-approver"},
+			reviewers: []string{"@root-reviewer"},
+			labels:    []string{"kind/repo"},
+			files:     []string{"OWNERS"},
+		},
+		{
+			path:      "pkg/storage/volume/mount.go",
+			approvers: []string{"@jane", "@john", "@root-approver"},
+			reviewers: []string{"@bob", "@root-reviewer"},
+			labels:    []string{"sig/storage", "kind/repo"},
+			files:     []string{"pkg/storage/OWNERS", "OWNERS"},
+		},
+		{
+			path:      "pkg/api/types.go",
+			approvers: []string{"@api-approver", "@root-approver"},
+			reviewers: []string{"@root-reviewer"},
+			labels:    []string{"kind/repo"},
+			files:     []string{"pkg/api/OWNERS", "OWNERS"},
+		},
+		{
+			path:      "pkg/api/v1/types.proto",
+			approvers: []string{"@api-approver", "@root-approver"},
+			reviewers: []string{"@ann", "@root-reviewer"},
+			labels:    []string{"area/proto", "kind/repo"},
+			files:     []string{"pkg/api/OWNERS", "OWNERS"},
+		},
+		{
+			path:      "vendor/github.com/lib/lib.go",
+			approvers: []string{"@vendor-bot"},
+			reviewers: []string{},
+			labels:    []string{},
+			files:     []string{"vendor/OWNERS"},
+		},
+	}
+	for _, tc := range testcases {
+		m := o.Find(tc.path)
+		if !reflect.DeepEqual(m.Approvers, tc.approvers) {
+			t.Errorf("%s : expected approvers %v got %v", tc.path, tc.approvers, m.Approvers)
+		}
+		if !reflect.DeepEqual(m.Reviewers, tc.reviewers) {
+			t.Errorf("%s : expected reviewers %v got %v", tc.path, tc.reviewers, m.Reviewers)
+		}
+		if !reflect.DeepEqual(m.Labels, tc.labels) {
+			t.Errorf("%s : expected labels %v got %v", tc.path, tc.labels, m.Labels)
+		}
+		if !reflect.DeepEqual(m.Files, tc.files) {
+			t.Errorf("%s : expected files %v got %v", tc.path, tc.files, m.Files)
+		}
+	}
+
+	f := o.File("pkg/storage/")
+	if f == nil || !reflect.DeepEqual(f.Filters[0].EmeritusApprovers, []string{"@old-timer"}) {
+		t.Errorf("expected the emeritus approvers of pkg/storage got %+v", f)
+	}
+	if o.File("vendor") == nil || !o.File("vendor").NoParentOwners {
+		t.Error("expected vendor to set no_parent_owners")
+	}
+}
+
+func TestKubernetesOwnersIndex(t *testing.T) {
+	o, errs := BuildFromKubernetesTree("fixtures/kubernetes")
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	co, errs := o.Index()
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	// The index gives the approvers and reviewers Prow would
+	paths := []string{"main.go", "pkg/storage/volume/mount.go", "pkg/api/types.go", "pkg/api/v1/types.proto", "vendor/github.com/lib/lib.go"}
+	for _, path := range paths {
+		if owners, approvers := co.FindOwners(path), o.FindApprovers(path); !reflect.DeepEqual(owners, approvers) {
+			t.Errorf("%s : expected owners %v got %v", path, approvers, owners)
+		}
+		if reviewers, expected := co.FindReviewers(path), o.FindReviewers(path); !reflect.DeepEqual(reviewers, expected) {
+			t.Errorf("%s : expected reviewers %v got %v", path, expected, reviewers)
+		}
+	}
+
+	m := co.Match("pkg/api/v1/types.proto")
+	if m.Rule.Pattern() != "/pkg/api/**/*.proto" || !reflect.DeepEqual(m.Rule.Reviewers(), []string{"@ann"}) {
+		t.Errorf("unexpected rule %s reviewed by %v", m.Rule.Pattern(), m.Rule.Reviewers())
+	}
+	var b bytes.Buffer
+	co.Serialize(&b)
+	if !strings.Contains(b.String(), "\n/vendor/\n/vendor/ @vendor-bot\n") {
+		t.Errorf("expected vendor/ to clear the owners of the root got %q", b.String())
+	}
+
+	filters := []struct {
+		filter  string
+		pattern string
+	}{
+		{filter: ".*", pattern: "/pkg/"},
+		{filter: "\\.go$", pattern: "/pkg/**/*.go"},
+		{filter: "^BUILD\\.bazel$", pattern: "/pkg/BUILD.bazel"},
+		{filter: "^testdata/", pattern: "/pkg/testdata/"},
+		{filter: "_test\\.go$", pattern: ""},
+	}
+	for _, tc := range filters {
+		entry, ok := filterEntry("pkg", tc.filter)
+		if ok != (tc.pattern != "") || (ok && entry.Pattern() != tc.pattern) {
+			t.Errorf("%s : expected the pattern %q got %v", tc.filter, tc.pattern, entry)
+		}
+	}
+}
+
+func TestBuildFromKubernetesTreeErrors(t *testing.T) {
+	testcases := []struct {
+		input    string
+		expected string
+	}{
+		{input: "approvers: [a]\nfilters:\n  \".*\":\n    reviewers: [b]\n", expected: "filters cannot be used along with"},
+		{input: "filters:\n  \"[\":\n    approvers: [a]\n", expected: "([) is an invalid filter"},
+		{input: "approvers: {a: b}\n", expected: "cannot unmarshal"},
+	}
+
+	for _, tc := range testcases {
+		root, err := ioutil.TempDir("", "owners")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(root, "OWNERS"), []byte(tc.input), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		o, errs := BuildFromKubernetesTree(root)
+		if o != nil || len(errs) == 0 || !strings.Contains(errs[0].Error(), tc.expected) {
+			t.Errorf("expected an error containing %q got %v", tc.expected, errs)
+		}
+		os.RemoveAll(root)
+	}
+}
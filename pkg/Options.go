@@ -0,0 +1,40 @@
+package codeowners
+
+// Option configures an index built by BuildIndex or BuildFromFile
+type Option func(*CodeOwners)
+
+// WithCaseInsensitive makes the index match paths regardless of their case,
+// for repositories developed on case-insensitive filesystems
+func WithCaseInsensitive() Option {
+	return func(t *CodeOwners) {
+		t.SetCaseInsensitive(true)
+	}
+}
+
+// WithDialect parses, validates and matches the CODEOWNERS file the way the
+// platform of the dialect does, and resolves owners with its resolution
+func WithDialect(d Dialect) Option {
+	return func(t *CodeOwners) {
+		t.dialect = d
+		t.patterns = map[string]Matcher{}
+		t.owners = map[string]Owner{}
+		t.SetResolution(d.Resolution())
+	}
+}
+
+// WithAliases resolves the owners of the rules and sections to the owner their
+// alias resolves to when looking up owners. Rules keep the owners they were
+// written with, use RewriteOwners to rewrite the file.
+func WithAliases(a *Aliases) Option {
+	return func(t *CodeOwners) {
+		t.aliases = a
+	}
+}
+
+// WithOwnerDirectory looks up the owners of the file in the directory while
+// building the index, owners which do not exist are reported as warnings
+func WithOwnerDirectory(d OwnerDirectory) Option {
+	return func(t *CodeOwners) {
+		t.directory = d
+	}
+}
@@ -0,0 +1,150 @@
+package codeowners
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// OwnerKind is the kind of an owner listed in a CODEOWNERS file
+type OwnerKind int
+
+const (
+	UserOwner        OwnerKind = iota // @username
+	GroupOwner                        // @org/team on GitHub, @group/subgroup on GitLab, @@Team on Bitbucket
+	NestedGroupOwner                  // @group/with-nested/subgroup on GitLab
+	RoleOwner                         // @@developer, @@maintainer or @@owner on GitLab
+	EmailOwner                        // jane@example.com
+	EveryoneOwner                     // * in Chromium OWNERS files, anyone may approve
+	UnknownOwner                      // not a valid owner for the dialect of the index
+)
+
+func (k OwnerKind) String() string {
+	switch k {
+	case UserOwner:
+		return "user"
+	case GroupOwner:
+		return "group"
+	case NestedGroupOwner:
+		return "nested group"
+	case RoleOwner:
+		return "role"
+	case EmailOwner:
+		return "email"
+	case EveryoneOwner:
+		return "everyone"
+	case UnknownOwner:
+		return "unknown"
+	}
+	return fmt.Sprintf("OwnerKind(%d)", int(k))
+}
+
+var (
+	rxEmail = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
+
+	// rxName matches a user, organization, team or group name
+	rxName = regexp.MustCompile(`^[A-Za-z0-9_](?:[A-Za-z0-9_.-]*[A-Za-z0-9_])?$`)
+
+	// roles are the GitLab project roles which may own files
+	roles = map[string]bool{
+		"developer":   true,
+		"developers":  true,
+		"maintainer":  true,
+		"maintainers": true,
+		"owner":       true,
+		"owners":      true,
+	}
+)
+
+// ClassifyOwner returns the kind of an owner, or an error explaining why it is
+// not a valid owner
+func ClassifyOwner(owner string) (OwnerKind, error) {
+	if len(owner) < 1 || len(owner) > 254 {
+		return 0, errors.New("owners must be between 1 and 254 characters")
+	}
+
+	if strings.HasPrefix(owner, "@@") {
+		if !roles[strings.ToLower(owner[2:])] {
+			return 0, fmt.Errorf("%s is not a role, use @@developer, @@maintainer or @@owner", owner)
+		}
+		return RoleOwner, nil
+	}
+
+	if strings.HasPrefix(owner, "@") {
+		names := strings.Split(owner[1:], "/")
+		for _, name := range names {
+			if !rxName.MatchString(name) {
+				return 0, fmt.Errorf("%s is not a valid user or group name", owner)
+			}
+		}
+		switch len(names) {
+		case 1:
+			return UserOwner, nil
+		case 2:
+			return GroupOwner, nil
+		}
+		return NestedGroupOwner, nil
+	}
+
+	if rxEmail.MatchString(owner) {
+		return EmailOwner, nil
+	}
+
+	return 0, fmt.Errorf("%s is neither a @name nor an email address", owner)
+}
+
+func isValidOwner(owner string) bool {
+	_, err := ClassifyOwner(owner)
+	return err == nil
+}
+
+// Owner is an owner listed in a CODEOWNERS file, split into its parts so
+// callers do not have to inspect the raw text
+type Owner struct {
+	Raw  string // owner as written, e.g @org/team
+	Kind OwnerKind
+	Org  string // parent organization or groups of a group owner, e.g org for @org/team
+	Name string // user, team, group or role name without @ signs, the address of an email owner
+}
+
+// ParseOwner parses an owner accepted by any platform
+func ParseOwner(raw string) (Owner, error) {
+	return parseOwner(raw, Generic)
+}
+
+// parseKnownOwner parses an owner like parseOwner, owners the dialect rejects
+// are kept with an UnknownOwner kind
+func parseKnownOwner(raw string, d Dialect) Owner {
+	o, err := parseOwner(raw, d)
+	if err != nil {
+		o = Owner{Raw: raw, Kind: UnknownOwner, Name: raw}
+		if raw == Everyone {
+			o.Kind = EveryoneOwner
+		}
+	}
+	return o
+}
+
+// parseOwner parses an owner accepted by the dialect
+func parseOwner(raw string, d Dialect) (Owner, error) {
+	kind, err := d.ClassifyOwner(raw)
+	if err != nil {
+		return Owner{}, err
+	}
+
+	o := Owner{Raw: raw, Kind: kind, Name: strings.TrimLeft(raw, "@")}
+	if kind == GroupOwner || kind == NestedGroupOwner {
+		if i := strings.LastIndex(o.Name, "/"); i >= 0 {
+			o.Org, o.Name = o.Name[:i], o.Name[i+1:]
+		}
+	}
+	if kind == EmailOwner {
+		o.Name = raw
+	}
+	return o, nil
+}
+
+func (o Owner) String() string {
+	return o.Raw
+}
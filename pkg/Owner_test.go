@@ -0,0 +1,113 @@
+package codeowners
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClassifyOwner(t *testing.T) {
+	testcases := []struct {
+		owner string
+		kind  OwnerKind
+		valid bool
+	}{
+		{owner: "@alecharmon", kind: UserOwner, valid: true},
+		{owner: "@jane.doe_2", kind: UserOwner, valid: true},
+		{owner: "@org/payments", kind: GroupOwner, valid: true},
+		{owner: "@group/with-nested/subgroup", kind: NestedGroupOwner, valid: true},
+		{owner: "@@developer", kind: RoleOwner, valid: true},
+		{owner: "@@Maintainers", kind: RoleOwner, valid: true},
+		{owner: "@@owner", kind: RoleOwner, valid: true},
+		{owner: "janedoe@gitlab.com", kind: EmailOwner, valid: true},
+		{owner: "@", valid: false},
+		{owner: "@@", valid: false},
+		{owner: "@@reporter", valid: false},
+		{owner: "@org/", valid: false},
+		{owner: "@org//team", valid: false},
+		{owner: "@user!", valid: false},
+		{owner: "@-user", valid: false},
+		{owner: "@jane@example.com", valid: false},
+		{owner: "this_does_not_match", valid: false},
+		{owner: "@" + strings.Repeat("a", 254), valid: false},
+	}
+
+	for _, tc := range testcases {
+		kind, err := ClassifyOwner(tc.owner)
+		if (err == nil) != tc.valid {
+			t.Errorf("%s : expected valid to be %v got %v", tc.owner, tc.valid, err)
+			continue
+		}
+		if tc.valid && kind != tc.kind {
+			t.Errorf("%s : expected a %s got a %s", tc.owner, tc.kind, kind)
+		}
+	}
+}
+
+func TestParseOwner(t *testing.T) {
+	testcases := []struct {
+		raw      string
+		expected Owner
+	}{
+		{raw: "@alecharmon", expected: Owner{Raw: "@alecharmon", Kind: UserOwner, Name: "alecharmon"}},
+		{raw: "@org/payments", expected: Owner{Raw: "@org/payments", Kind: GroupOwner, Org: "org", Name: "payments"}},
+		{raw: "@group/nested/sub", expected: Owner{Raw: "@group/nested/sub", Kind: NestedGroupOwner, Org: "group/nested", Name: "sub"}},
+		{raw: "@@maintainer", expected: Owner{Raw: "@@maintainer", Kind: RoleOwner, Name: "maintainer"}},
+		{raw: "jane@example.com", expected: Owner{Raw: "jane@example.com", Kind: EmailOwner, Name: "jane@example.com"}},
+	}
+
+	for _, tc := range testcases {
+		o, err := ParseOwner(tc.raw)
+		if err != nil {
+			t.Errorf("%s : unexpected error %v", tc.raw, err)
+			continue
+		}
+		if o != tc.expected {
+			t.Errorf("%s : expected %+v got %+v", tc.raw, tc.expected, o)
+		}
+	}
+
+	if _, err := ParseOwner("not-an-owner"); err == nil {
+		t.Error("expected an error for an invalid owner")
+	}
+}
+
+func TestFindParsedOwners(t *testing.T) {
+	co, errs := BuildFromFile("fixtures/testCODEOWNERS_Bitbucket", WithDialect(Bitbucket))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	expected := []Owner{
+		{Raw: "@@Frontend", Kind: GroupOwner, Name: "Frontend"},
+		{Raw: "@@Backend", Kind: GroupOwner, Name: "Backend"},
+	}
+	if owners := co.FindParsedOwners("api/docs/index.md"); !reflect.DeepEqual(owners, expected) {
+		t.Errorf("expected %+v got %+v", expected, owners)
+	}
+	m := co.Match("api/docs/index.md")
+	if !reflect.DeepEqual(m.Parsed, expected) {
+		t.Errorf("expected the match to hold %+v got %+v", expected, m.Parsed)
+	}
+	if owners := m.Rule.ParsedOwners(); !reflect.DeepEqual(owners, expected) {
+		t.Errorf("expected the rule to hold %+v got %+v", expected, owners)
+	}
+	if _, ok := m.Rule.parsed["@@Frontend"]; !ok {
+		t.Error("expected the owners of the rule to be parsed when the index was built")
+	}
+	if groups := co.FindOwnerGroups("api/docs/index.md"); len(groups) != 1 || !reflect.DeepEqual(groups[0].Parsed, expected) {
+		t.Errorf("expected a group holding %+v got %+v", expected, groups)
+	}
+
+	co.AddOwner("legacy/", "legacy-team", Everyone)
+	expected = []Owner{
+		{Raw: "legacy-team", Kind: UnknownOwner, Name: "legacy-team"},
+		{Raw: Everyone, Kind: EveryoneOwner, Name: Everyone},
+	}
+	if owners := co.FindParsedOwners("legacy/main.go"); !reflect.DeepEqual(owners, expected) {
+		t.Errorf("expected %+v got %+v", expected, owners)
+	}
+	if owners := co.Match("legacy/main.go").Rule.ParsedOwners(); !reflect.DeepEqual(owners, expected) {
+		t.Errorf("expected the added rule to hold %+v got %+v", expected, owners)
+	}
+}
@@ -1,21 +1,24 @@
 package codeowners
 
 import (
-	"errors"
-	"fmt"
 	"io"
-	"io/ioutil"
-	"log"
 	"path/filepath"
-	"regexp"
 	"strings"
 )
 
 type Entry struct {
-	path    string
-	suffix  PathSufix
-	comment string
-	owners  []string
+	path      string
+	anchored  bool // path started with a slash and only matches from the repository root
+	suffix    PathSufix
+	comment   string
+	owners    []string
+	section   *Section         // GitLab section the entry belongs to, nil outside of sections
+	file      string           // file the entry was parsed from when the index was built from several files
+	line      int              // line of the entry in its CODEOWNERS file, 0 when it was not parsed from one
+	order     int              // position of the entry in its index, later entries take precedence
+	parsed    map[string]Owner // owners parsed by the index when it loaded the entry, shared with the index
+	aliases   *Aliases         // aliases of the index owners resolve through, nil without aliases
+	reviewers []string         // reviewers kept from a Kubernetes OWNERS file
 }
 
 func NewEntry() *Entry {
@@ -29,80 +32,392 @@ func NewEntry() *Entry {
 type Parser struct {
 	s   *Scanner
 	buf struct {
-		tok Token  // last read token
-		lit string // last read literal
-		n   int    // buffer size (max=1)
+		tok Token    // last read token
+		lit string   // last read literal
+		pos Position // position of the last read token
+		n   int      // buffer size (max=1)
 	}
+	section *Section                    // section the following rules belong to
+	groups  map[string]*GroupDefinition // Bitbucket groups defined so far
+	dialect Dialect
+	done    bool // the last line of the input has been parsed
 }
 
-// NewParser returns a new instance of Parser.
+// NewParser returns a new instance of Parser accepting the syntax of every platform.
 func NewParser(r io.Reader) *Parser {
-	return &Parser{s: NewScanner(r)}
+	return NewDialectParser(r, Generic)
+}
+
+// NewDialectParser returns a new instance of Parser for the syntax of a platform.
+func NewDialectParser(r io.Reader, d Dialect) *Parser {
+	return &Parser{s: NewScanner(r), dialect: d, groups: map[string]*GroupDefinition{}}
 }
 
 // Parse parses a line from a codeowners file.
 func (p *Parser) Parse() (*Entry, error) {
-	entry := NewEntry()
-	lineByte, err := ioutil.ReadAll(p.s.r)
+	l, err := p.parseLine()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	line := string(lineByte)
-	if line[0] == '#' {
-		entry.comment = line
-		entry.suffix = PathSufix(None)
-		return entry, nil
+	if l.entry == nil {
+		return NewEntry(), nil
 	}
+	return l.entry, nil
+}
 
-	parts := strings.Fields(line)
+// Next returns the next rule or comment of the file, skipping blank lines and
+// section headers, and io.EOF once every line has been parsed. Only the line
+// being parsed is held in memory. A *Diagnostic is returned for a line which
+// failed to parse and the following call carries on with the next line, any
+// other error is a read error which ends parsing.
+func (p *Parser) Next() (*Entry, error) {
+	for !p.done {
+		l, err := p.parseLine()
+		if l.eol == "" {
+			p.done = true
+			if readErr := p.s.Err(); readErr != nil {
+				return nil, readErr
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if l.entry != nil {
+			l.entry.line = l.number
+			return l.entry, nil
+		}
+	}
+	return nil, io.EOF
+}
 
-	if len(parts) < 2 {
-		if isValidOwner(parts[0]) {
-			return nil, errors.New("Missing path for entry")
+// ParseReader parses a CODEOWNERS file from r one line at a time, calling fn
+// with every rule and comment, so files of any size are parsed with memory
+// bounded by their longest line. Parsing stops at a read error or at the first
+// error returned by fn, which is returned after the diagnostics of the lines
+// parsed until then. Options choose the dialect of the file.
+func ParseReader(r io.Reader, fn func(*Entry) error, opts ...Option) []error {
+	p := NewDialectParser(r, newCodeOwners(opts...).dialect)
+	errors := []error{}
+	for {
+		entry, err := p.Next()
+		if err == io.EOF {
+			break
+		}
+		if _, ok := err.(*Diagnostic); ok {
+			errors = append(errors, err)
+			continue
+		}
+		if err == nil {
+			err = fn(entry)
+		}
+		if err != nil {
+			errors = append(errors, err)
+			break
 		}
-		return nil, errors.New("Invalid entry")
 	}
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
 
-	path := parts[0]
+// ParseFile parses every line of a codeowners file. Blank lines are skipped
+// and comment lines are returned as entries with a None suffix. The returned
+// errors are *Diagnostic.
+func (p *Parser) ParseFile() ([]*Entry, []error) {
+	doc, errors := p.ParseDocument()
+	return doc.Entries(), errors
+}
+
+// ParseDocument parses a whole codeowners file into a Document which keeps
+// every line as it was written, including blank lines, comments and lines
+// which failed to parse. The returned errors are *Diagnostic, apart from a
+// read error which ends the document.
+func (p *Parser) ParseDocument() (*Document, []error) {
+	doc := newDocument()
+	errors := []error{}
+	for {
+		l, err := p.parseLine()
+		if l.eol == "" && p.s.Err() != nil {
+			// The line was cut short by the read error
+			break
+		}
+		if err != nil {
+			errors = append(errors, err)
+		}
+		if l.entry != nil {
+			l.entry.line = l.number
+		}
+		doc.lines = append(doc.lines, l)
+		if l.eol == "" {
+			break
+		}
+	}
+	if err := p.s.Err(); err != nil {
+		errors = append(errors, err)
+	}
+	doc.bom = p.s.HasBOM()
+
+	// The final line is only kept when it has content, an input ending with a
+	// line ending does not hold an extra empty line
+	if len(doc.lines) > 0 && doc.lines[len(doc.lines)-1].text == "" {
+		doc.lines = doc.lines[:len(doc.lines)-1]
+	}
+	doc.snapshot()
+	return doc, errors
+}
+
+// parseLine parses the tokens up to and including the end of the line. The
+// line has no eol once the end of the input has been reached.
+//
+//	line  = [ WS ] [ path { WS owner } [ WS ] ] [ COMMENT ] ( EOL | EOF )
+func (p *Parser) parseLine() (*docLine, error) {
+	entry := NewEntry()
+	l := &docLine{number: p.peekPosition().Line}
+	var text, content strings.Builder
+	words := []word{}
+	for {
+		tok, lit := p.scan()
+		switch tok {
+		case IDENT:
+			words = append(words, word{lit: lit, pos: p.buf.pos})
+		case COMMENT:
+			entry.comment = lit
+		case EOL:
+			l.eol = lit
+		}
+		if tok == EOL || tok == EOF {
+			break
+		}
+		text.WriteString(lit)
+		if tok != COMMENT {
+			content.WriteString(lit)
+		}
+	}
+	l.text = text.String()
+
+	if len(words) == 0 {
+		if entry.comment != "" {
+			l.entry = entry
+		}
+		return l, nil
+	}
+
+	if d, ok := p.dialect.(groupDialect); ok && d.ownerGroups() {
+		if strings.HasPrefix(words[0].lit, "@@@") {
+			group, err := p.parseGroupDefinition(words)
+			if err != nil {
+				return l, err
+			}
+			group.line = l.number
+			l.group = group
+			p.groups[group.Name] = group
+			return l, nil
+		}
+		check, err := p.parseMergeCheck(strings.TrimSpace(content.String()), words)
+		if err != nil || check != nil {
+			if check != nil {
+				check.line = l.number
+				l.check = check
+			}
+			return l, err
+		}
+	}
+
+	// Dialects without sections read a header as a path
+	if p.dialect.Sections() {
+		if section, n := parseSectionHeader(strings.TrimSpace(content.String())); section != nil {
+			section.line = l.number
+			section.comment = entry.comment
+			headerEnd := words[0].pos.Offset + n
+			for _, w := range words {
+				if w.pos.Offset < headerEnd {
+					continue
+				}
+				if err := p.checkOwner(w); err != nil {
+					return l, err
+				}
+				section.DefaultOwners = append(section.DefaultOwners, w.lit)
+			}
+			l.section = section
+			p.section = section
+			return l, nil
+		}
+	}
+	entry.section = p.section
+
+	// A path without owners is valid and removes the ownership of the files it matches
+	if len(words) == 1 && isValidOwner(words[0].lit) {
+		return l, newDiagnostic(CodeMissingPath, words[0].pos, words[0].lit, "Missing path for entry")
+	}
+
+	path := words[0].lit
 
 	if path[0] == '/' {
+		entry.anchored = true
 		path = path[1:]
 	}
 
 	entry.path = path
 	entry.suffix = DetermineSuffix(entry.path)
 
-	for i, p := range parts[1:] {
-		if p[0] == '#' {
-			entry.comment = strings.Join(parts[i+1:], " ")
-			return entry, nil
-		}
-		if isValidOwner(p) == false {
-			return nil, fmt.Errorf("(%s) is an invalid owner", p)
+	for _, w := range words[1:] {
+		if err := p.checkOwner(w); err != nil {
+			return l, err
 		}
-		entry.owners = append(entry.owners, p)
+		entry.owners = append(entry.owners, w.lit)
 	}
-	return entry, nil
+
+	// The platform skips rules it does not support, the line is kept as written
+	if err := p.dialect.CheckPattern(words[0].lit); err != nil {
+		d := newDiagnostic(CodeUnsupportedPattern, words[0].pos, words[0].lit, "%s", err)
+		d.Severity = SeverityWarning
+		return l, d
+	}
+	l.entry = entry
+	return l, nil
 }
 
-func isValidOwner(owner string) bool {
-	if len(owner) < 1 || len(owner) > 254 {
-		return false
+// checkOwner returns a diagnostic when the dialect does not accept the owner
+func (p *Parser) checkOwner(w word) error {
+	kind, err := p.dialect.ClassifyOwner(w.lit)
+	if err != nil {
+		return newDiagnostic(CodeInvalidOwner, w.pos, w.lit, "(%s) is an invalid owner", w.lit)
 	}
+	if d, ok := p.dialect.(groupDialect); ok && d.ownerGroups() && kind == GroupOwner && p.groups[w.lit[2:]] == nil {
+		return newDiagnostic(CodeUndefinedGroup, w.pos, w.lit, "%s is not defined", w.lit)
+	}
+	return nil
+}
+
+// word is an IDENT token and its position
+type word struct {
+	lit string
+	pos Position
+}
 
-	//Does the owner start with a @ and only have one => @owner-name
-	if strings.Index(owner, "@") == 0 && strings.Index(owner, "@") == strings.LastIndex(owner, "@") {
-		return true
+// scan returns the next token from the underlying scanner.
+// If a token has been unscanned then read that instead.
+func (p *Parser) scan() (tok Token, lit string) {
+	// If we have a token on the buffer, then return it.
+	if p.buf.n != 0 {
+		p.buf.n = 0
+		return p.buf.tok, p.buf.lit
 	}
 
-	//Is it a valid email
-	var rxEmail = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
+	// Otherwise read the next token from the scanner and save it to the buffer.
+	tok, lit = p.s.Scan()
+	p.buf.tok, p.buf.lit, p.buf.pos = tok, lit, p.s.Position()
+
+	return
+}
+
+// unscan pushes the previously read token back onto the buffer.
+func (p *Parser) unscan() { p.buf.n = 1 }
+
+// peekPosition returns the position of the next token.
+func (p *Parser) peekPosition() Position {
+	p.scan()
+	p.unscan()
+	return p.buf.pos
+}
 
-	if rxEmail.MatchString(owner) {
-		return true
+// escapePath escapes the characters of a path that would otherwise end the
+// path or start a comment when written to a CODEOWNERS file
+func escapePath(path string) string {
+	var b strings.Builder
+	escaped := false
+	for i, ch := range path {
+		switch {
+		case escaped:
+			escaped = false
+		case ch == '\\':
+			escaped = true
+		case ch == ' ' || ch == '\t' || (ch == '#' && i == 0):
+			b.WriteRune('\\')
+		}
+		b.WriteRune(ch)
 	}
+	return b.String()
+}
+
+// Pattern returns the path pattern of the entry as written in the CODEOWNERS file
+func (e *Entry) Pattern() string {
+	return e.rawPath()
+}
 
-	return false
+// Owners returns the owners of the entry
+func (e *Entry) Owners() []string {
+	return append([]string{}, e.owners...)
+}
+
+// ParsedOwners returns the owners of the entry as parsed by its index,
+// according to the dialect of the index. Owners the dialect rejects have an
+// UnknownOwner kind. Entries outside of an index are parsed on every call.
+func (e *Entry) ParsedOwners() []Owner {
+	owners := []Owner{}
+	for _, raw := range e.owners {
+		o, ok := e.parsed[raw]
+		if !ok {
+			o = parseKnownOwner(raw, Generic)
+		}
+		owners = append(owners, o)
+	}
+	return owners
+}
+
+// Suffix returns the kind of path the entry matches
+func (e *Entry) Suffix() PathSufix {
+	return e.suffix
+}
+
+// Comment returns the inline comment of the entry including its leading #
+func (e *Entry) Comment() string {
+	return e.comment
+}
+
+// Section returns the GitLab section of the entry, nil outside of sections
+func (e *Entry) Section() *Section {
+	return e.section
+}
+
+// effectiveOwners returns the owners of the entry, an entry without owners in
+// a section with default owners is owned by the section defaults. Owners are
+// resolved through the aliases of the index.
+func (e *Entry) effectiveOwners() []string {
+	owners := e.owners
+	if len(owners) == 0 && e.section != nil {
+		owners = e.section.DefaultOwners
+	}
+	if e.aliases != nil {
+		return e.aliases.resolveAll(owners)
+	}
+	return owners
+}
+
+// Line returns the line number of the entry in its CODEOWNERS file
+func (e *Entry) Line() int {
+	return e.line
+}
+
+// File returns the file the entry was parsed from when the index was compiled
+// from fragments, empty otherwise
+func (e *Entry) File() string {
+	return e.file
+}
+
+// Reviewers returns the reviewers of the entry when it was converted from a
+// Kubernetes OWNERS file, CODEOWNERS files have no reviewers
+func (e *Entry) Reviewers() []string {
+	return append([]string{}, e.reviewers...)
+}
+
+// rawPath returns the path of the entry as it is written in a CODEOWNERS file
+func (e *Entry) rawPath() string {
+	if e.anchored {
+		return "/" + escapePath(e.path)
+	}
+	return escapePath(e.path)
 }
 
 // DetermineSuffix assings a sufix to a given path
@@ -110,6 +425,11 @@ func DetermineSuffix(path string) PathSufix {
 	base := filepath.Base(path)
 	ext := filepath.Ext(path)
 
+	// "docs/" and "docs/**" both own everything below docs, "/" owns everything
+	if path == "" || strings.HasSuffix(path, "/") || base == "**" {
+		return PathSufix(Recursive)
+	}
+
 	baseFirstCh := []rune(base)[0]
 	if baseFirstCh == '*' && ext != "" {
 		return PathSufix(Type)
@@ -119,9 +439,5 @@ func DetermineSuffix(path string) PathSufix {
 		return PathSufix(Flat)
 	}
 
-	if pathR := []rune(path); pathR[len(pathR)-1] == '/' {
-		return PathSufix(Recursive)
-	}
-
 	return PathSufix(Absolute)
 }
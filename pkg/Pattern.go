@@ -0,0 +1,210 @@
+package codeowners
+
+import (
+	"path"
+	"strings"
+)
+
+// Pattern is a compiled CODEOWNERS path pattern. It follows the gitignore
+// glob rules documented for CODEOWNERS files:
+//
+//   - "*" matches anything except a slash, "?" matches a single character
+//     and "[...]" matches a character class ("[!...]" negates it)
+//   - "**" matches zero or more whole directories
+//   - a pattern with a slash at its start or in its middle is anchored to the
+//     repository root, otherwise it matches at any depth
+//   - a pattern ending with a slash matches directories and their contents.
+//     A path without a trailing slash may name a directory, so "apps/"
+//     matches the path "apps", unless the last segment of the pattern has a
+//     wildcard, e.g "build-*/" does not match "build-1" but matches "build-1/"
+//   - a pattern whose last segment contains a wildcard only matches the
+//     files it names, e.g "docs/*" does not match "docs/build/index.md"
+type Pattern struct {
+	raw        string
+	segments   []string
+	anchored   bool
+	dirOnly    bool
+	wildLeaf   bool
+	matchesAll bool
+	foldCase   bool
+}
+
+// CompilePattern compiles a CODEOWNERS path pattern
+func CompilePattern(raw string) *Pattern {
+	return compilePattern(raw, false)
+}
+
+// CompileCaseInsensitivePattern compiles a CODEOWNERS path pattern which
+// matches paths regardless of their case
+func CompileCaseInsensitivePattern(raw string) *Pattern {
+	return compilePattern(raw, true)
+}
+
+func compilePattern(raw string, foldCase bool) *Pattern {
+	p := &Pattern{raw: raw, foldCase: foldCase}
+
+	pattern := raw
+	if foldCase {
+		pattern = strings.ToLower(pattern)
+	}
+	if strings.HasPrefix(pattern, "/") {
+		p.anchored = true
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") && !strings.HasSuffix(pattern, "\\/") {
+		p.dirOnly = true
+		pattern = pattern[:len(pattern)-1]
+	}
+	if strings.Contains(pattern, "/") {
+		p.anchored = true
+	}
+
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == "" {
+			continue
+		}
+		// "[!...]" is the gitignore spelling of a negated class
+		segment = strings.Replace(segment, "[!", "[^", -1)
+		p.segments = append(p.segments, segment)
+	}
+
+	if len(p.segments) == 0 {
+		p.matchesAll = true
+		return p
+	}
+	if !p.anchored && len(p.segments) == 1 && (p.segments[0] == "*" || p.segments[0] == "**") {
+		p.matchesAll = true
+	}
+
+	leaf := p.segments[len(p.segments)-1]
+	p.wildLeaf = leaf != "**" && hasWildcard(leaf)
+
+	if !p.anchored {
+		p.segments = append([]string{"**"}, p.segments...)
+	}
+	return p
+}
+
+// String returns the pattern as it was written
+func (p *Pattern) String() string {
+	return p.raw
+}
+
+// Match reports whether the given path is matched by the pattern. A path
+// ending with a slash is treated as a directory.
+func (p *Pattern) Match(filePath string) bool {
+	if p.matchesAll {
+		return true
+	}
+
+	isDir := strings.HasSuffix(filePath, "/")
+	if p.foldCase {
+		filePath = strings.ToLower(filePath)
+	}
+	names := splitPath(filePath)
+	if len(names) == 0 {
+		return false
+	}
+
+	if p.wildLeaf && !p.dirOnly {
+		// Wildcard leaves name files, never directories or their contents
+		if isDir {
+			return false
+		}
+		return matchSegments(p.segments, names)
+	}
+
+	// Any leading directory matched by the pattern owns everything below it
+	for i := 1; i < len(names); i++ {
+		if matchSegments(p.segments, names[:i]) {
+			return true
+		}
+	}
+	// A path given without a trailing slash may still name a directory, which
+	// is assumed unless the pattern only describes directories by wildcard
+	if p.dirOnly && p.wildLeaf && !isDir {
+		return false
+	}
+	return matchSegments(p.segments, names)
+}
+
+// MatchesBelow reports whether the pattern can match any path inside the
+// given directory
+func (p *Pattern) MatchesBelow(dir string) bool {
+	if p.matchesAll {
+		return true
+	}
+	if p.foldCase {
+		dir = strings.ToLower(dir)
+	}
+	names := splitPath(dir)
+	if len(names) == 0 {
+		return true
+	}
+	if (!p.wildLeaf || p.dirOnly) && p.Match(strings.Join(names, "/")+"/") {
+		return true
+	}
+	return matchPrefix(p.segments, names)
+}
+
+// matchPrefix reports whether the names can be consumed by the start of the
+// pattern, leaving part of the pattern to match something below them
+func matchPrefix(pattern, names []string) bool {
+	for len(names) > 0 {
+		if len(pattern) == 0 {
+			return false
+		}
+		if pattern[0] == "**" {
+			return true
+		}
+		if ok, err := path.Match(pattern[0], names[0]); err != nil || !ok {
+			return false
+		}
+		pattern, names = pattern[1:], names[1:]
+	}
+	return len(pattern) > 0
+}
+
+func matchSegments(pattern, names []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(names); i++ {
+				if matchSegments(rest, names[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(names) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], names[0]); err != nil || !ok {
+			return false
+		}
+		pattern, names = pattern[1:], names[1:]
+	}
+	return len(names) == 0
+}
+
+func splitPath(filePath string) []string {
+	names := []string{}
+	for _, name := range strings.Split(filePath, "/") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func hasWildcard(segment string) bool {
+	for i := 0; i < len(segment); i++ {
+		switch segment[i] {
+		case '\\':
+			i++
+		case '*', '?', '[':
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package codeowners
+
+import (
+	"testing"
+)
+
+func TestPatternMatch(t *testing.T) {
+	testcases := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{pattern: "*", path: "README", match: true},
+		{pattern: "*", path: "app/lib/index.js", match: true},
+		{pattern: "*", path: "app/lib/", match: true},
+		{pattern: "*.js", path: "index.js", match: true},
+		{pattern: "*.js", path: "app/lib/index.js", match: true},
+		{pattern: "*.js", path: "app/lib/index.jsx", match: false},
+		{pattern: "*.js", path: "app/lib.js/", match: false},
+		{pattern: "README", path: "README", match: true},
+		{pattern: "README", path: "docs/README", match: true},
+		{pattern: "README", path: "docs/README.md", match: false},
+		{pattern: "app/", path: "app/index.js", match: true},
+		{pattern: "app/", path: "vendor/app/index.js", match: true},
+		{pattern: "app/", path: "application/index.js", match: false},
+		// A path without a trailing slash may name a directory
+		{pattern: "apps/", path: "apps", match: true},
+		{pattern: "apps/", path: "apps/", match: true},
+		{pattern: "build-*/", path: "build-1", match: false},
+		{pattern: "build-*/", path: "build-1/", match: true},
+		{pattern: "build-*/", path: "build-1/main.go", match: true},
+		{pattern: "app/lib/", path: "app/lib/network/index.js", match: true},
+		{pattern: "app/lib/", path: "vendor/app/lib/network/index.js", match: false},
+		{pattern: "/docs/", path: "docs/index.md", match: true},
+		{pattern: "/docs/", path: "app/docs/index.md", match: false},
+		{pattern: "docs/*", path: "docs/index.md", match: true},
+		{pattern: "docs/*", path: "docs/build/index.md", match: false},
+		{pattern: "docs/*", path: "docs/build/", match: false},
+		{pattern: "docs/**/*.md", path: "docs/index.md", match: true},
+		{pattern: "docs/**/*.md", path: "docs/a/b/c/index.md", match: true},
+		{pattern: "docs/**/*.md", path: "docs/a/b/c/index.go", match: false},
+		{pattern: "docs/**/*.md", path: "app/docs/index.md", match: false},
+		{pattern: "**/testdata/", path: "testdata/golden.json", match: true},
+		{pattern: "**/testdata/", path: "pkg/parser/testdata/golden.json", match: true},
+		{pattern: "**/logs", path: "deeply/nested/logs", match: true},
+		{pattern: "apps/**", path: "apps/web/index.js", match: true},
+		{pattern: "apps/**", path: "web/apps/index.js", match: false},
+		{pattern: "src/?.go", path: "src/a.go", match: true},
+		{pattern: "src/?.go", path: "src/ab.go", match: false},
+		{pattern: "[Mm]akefile", path: "Makefile", match: true},
+		{pattern: "[Mm]akefile", path: "tools/makefile", match: true},
+		{pattern: "[Mm]akefile", path: "Rakefile", match: false},
+		{pattern: "[!M]akefile", path: "Rakefile", match: true},
+		{pattern: "[!M]akefile", path: "Makefile", match: false},
+		{pattern: "\\#file_with_pound.rb", path: "#file_with_pound.rb", match: true},
+	}
+
+	for _, tc := range testcases {
+		if got := CompilePattern(tc.pattern).Match(tc.path); got != tc.match {
+			t.Errorf("pattern %s, path %s: expected %v got %v", tc.pattern, tc.path, tc.match, got)
+		}
+	}
+}
+
+func TestFindOwnersGlob(t *testing.T) {
+	co, err := BuildIndex([]byte(`docs/**/*.md @docs
+**/testdata/ @fixtures
+src/?.go @short
+[Mm]akefile @build
+`))
+	if err != nil {
+		t.Fatalf("expecting a non error %v", err)
+	}
+	testcases := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "docs/guides/setup.md", expected: []string{"@docs"}},
+		{input: "pkg/parser/testdata/input.txt", expected: []string{"@fixtures"}},
+		{input: "src/a.go", expected: []string{"@short"}},
+		{input: "src/main.go", expected: []string{}},
+		{input: "tools/makefile", expected: []string{"@build"}},
+	}
+
+	for _, tc := range testcases {
+		if out := co.FindOwners(tc.input); !sameStringSlice(out, tc.expected) {
+			t.Errorf("%s : expected %v got %v", tc.input, tc.expected, out)
+		}
+	}
+}
+
+func TestCaseInsensitivePatternMatch(t *testing.T) {
+	testcases := []struct {
+		pattern   string
+		path      string
+		match     bool
+		sensitive bool
+	}{
+		{pattern: "README", path: "readme", match: true},
+		{pattern: "*.MD", path: "docs/index.md", match: true},
+		{pattern: "*.md", path: "docs/INDEX.MD", match: true},
+		{pattern: "/Docs/", path: "DOCS/index.md", match: true},
+		{pattern: "[A-C]pp/", path: "app/index.js", match: true},
+		{pattern: "*.md", path: "docs/index.mdx", match: false},
+		{pattern: "*.md", path: "docs/index.md", match: true, sensitive: true},
+	}
+
+	for _, tc := range testcases {
+		if got := CompileCaseInsensitivePattern(tc.pattern).Match(tc.path); got != tc.match {
+			t.Errorf("pattern %s, path %s: expected %v got %v", tc.pattern, tc.path, tc.match, got)
+		}
+		if got := CompilePattern(tc.pattern).Match(tc.path); got != tc.sensitive {
+			t.Errorf("pattern %s, path %s: expected case sensitive match %v got %v", tc.pattern, tc.path, tc.sensitive, got)
+		}
+	}
+}
+
+func TestPatternMatchesBelow(t *testing.T) {
+	testcases := []struct {
+		pattern string
+		dir     string
+		match   bool
+	}{
+		{pattern: "*", dir: "app/lib", match: true},
+		{pattern: "*.js", dir: "app/lib", match: true},
+		{pattern: "app/", dir: "app/lib", match: true},
+		{pattern: "app/lib/network/", dir: "app/lib", match: true},
+		{pattern: "app/lib/network/", dir: "app/vendor", match: false},
+		{pattern: "/docs/", dir: "app/docs", match: false},
+		{pattern: "docs/*", dir: "docs", match: true},
+		{pattern: "docs/*", dir: "docs/build", match: false},
+		{pattern: "docs/**/*.md", dir: "docs/a/b", match: true},
+		{pattern: "app/*/index.js", dir: "app/lib", match: true},
+		{pattern: "app/*/index.js", dir: "app/lib/network", match: false},
+		{pattern: "README", dir: "app", match: true},
+		{pattern: "app/README", dir: "", match: true},
+	}
+
+	for _, tc := range testcases {
+		if got := CompilePattern(tc.pattern).MatchesBelow(tc.dir); got != tc.match {
+			t.Errorf("pattern %s, dir %s: expected %v got %v", tc.pattern, tc.dir, tc.match, got)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package codeowners
+
+// Resolution controls how the owners of several matching entries are combined
+type Resolution int
+
+const (
+	LastMatch Resolution = iota // Only the last matching entry in the file applies, as on GitHub and GitLab
+	Union                       // Every matching entry contributes its owners, useful for audits
+)
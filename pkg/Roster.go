@@ -0,0 +1,151 @@
+package codeowners
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Roster lists the members of teams, so owners such as @org/payments can be
+// expanded to the people behind them. It is loaded from a YAML or JSON file,
+// e.g
+//
+//	teams:
+//	  "@org/payments":
+//	    members: ["@alice", "@bob"]
+//	    teams: ["@org/payments-oncall"]
+//	  "@org/payments-oncall":
+//	    members: ["@carol"]
+//
+// Team names are matched regardless of case.
+type Roster struct {
+	teams map[string]*RosterTeam
+}
+
+// RosterTeam is a team of a Roster
+type RosterTeam struct {
+	Name    string   `yaml:"-" json:"-"`
+	Members []string `yaml:"members" json:"members"` // users and emails
+	Teams   []string `yaml:"teams" json:"teams"`     // child teams whose members belong to the team
+}
+
+// Member is a person owning a path, either listed directly or through a team
+type Member struct {
+	Owner string   // user or email of the person
+	Via   []string // teams the person was reached through, outermost first, empty when listed directly
+}
+
+type rosterFile struct {
+	Teams map[string]*RosterTeam `yaml:"teams" json:"teams"`
+}
+
+// LoadRoster reads a roster from a JSON or YAML file
+func LoadRoster(filePath string) (*Roster, error) {
+	f := rosterFile{}
+	if err := decodeConfigFile(filePath, &f); err != nil {
+		return nil, err
+	}
+	return NewRoster(f.Teams)
+}
+
+// NewRoster creates a roster from teams keyed by their name. It returns an
+// error when a team has an unknown child team or teams contain each other,
+// either as child teams or as members.
+func NewRoster(teams map[string]*RosterTeam) (*Roster, error) {
+	r := &Roster{teams: map[string]*RosterTeam{}}
+	for name, team := range teams {
+		if team == nil {
+			team = &RosterTeam{}
+		}
+		team.Name = name
+		r.teams[strings.ToLower(name)] = team
+	}
+
+	// Check the teams in a stable order so the reported cycle does not vary
+	names := []string{}
+	for name := range r.teams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	done := map[string]bool{}
+	var visit func(team *RosterTeam, path []string) error
+	visit = func(team *RosterTeam, path []string) error {
+		key := strings.ToLower(team.Name)
+		for i, name := range path {
+			if strings.ToLower(name) == key {
+				return fmt.Errorf("teams contain each other: %s", strings.Join(append(path[i:], team.Name), " -> "))
+			}
+		}
+		if done[key] {
+			return nil
+		}
+		for _, child := range team.Teams {
+			c, ok := r.teams[strings.ToLower(child)]
+			if !ok {
+				return fmt.Errorf("%s has an unknown child team %s", team.Name, child)
+			}
+			if err := visit(c, append(path, team.Name)); err != nil {
+				return err
+			}
+		}
+		// Members naming a team of the roster are expanded like child teams
+		for _, member := range team.Members {
+			if c, ok := r.teams[strings.ToLower(member)]; ok {
+				if err := visit(c, append(path, team.Name)); err != nil {
+					return err
+				}
+			}
+		}
+		done[key] = true
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(r.teams[name], []string{}); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Team returns the team with the name, nil when the roster does not list it
+func (r *Roster) Team(name string) *RosterTeam {
+	return r.teams[strings.ToLower(name)]
+}
+
+// Expand replaces the teams among the owners with their members, including
+// the members of their child teams. Every person is returned once, with the
+// first teams it was reached through. Owners which are not teams of the roster
+// are returned as they are.
+func (r *Roster) Expand(owners []string) []Member {
+	members := []Member{}
+	seen := map[string]bool{}
+	var expand func(owner string, via []string)
+	expand = func(owner string, via []string) {
+		team := r.Team(owner)
+		if team == nil {
+			if !seen[owner] {
+				seen[owner] = true
+				members = append(members, Member{Owner: owner, Via: via})
+			}
+			return
+		}
+		via = append(append([]string{}, via...), team.Name)
+		for _, member := range team.Members {
+			expand(member, via)
+		}
+		for _, child := range team.Teams {
+			expand(child, via)
+		}
+	}
+	for _, owner := range owners {
+		expand(owner, []string{})
+	}
+	return members
+}
+
+// FindMembers returns the people owning a path, expanding the teams returned
+// by FindOwners with the roster
+func (t *CodeOwners) FindMembers(path string, r *Roster) []Member {
+	return r.Expand(t.FindOwners(path))
+}
@@ -0,0 +1,76 @@
+package codeowners
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRoster(t *testing.T) {
+	co, errs := BuildIndex([]byte("* @org/platform\npayments/ @org/Payments @frank\n"))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	for _, file := range []string{"fixtures/roster/teams.yaml", "fixtures/roster/teams.json"} {
+		r, err := LoadRoster(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []Member{
+			{Owner: "@alice", Via: []string{"@org/payments"}},
+			{Owner: "@bob", Via: []string{"@org/payments"}},
+			{Owner: "@carol", Via: []string{"@org/payments", "@org/payments-oncall"}},
+			{Owner: "@frank", Via: []string{}},
+		}
+		if members := co.FindMembers("payments/api.go", r); !reflect.DeepEqual(members, expected) {
+			t.Errorf("%s : expected %v got %v", file, expected, members)
+		}
+
+		expected = []Member{
+			{Owner: "@dave", Via: []string{"@org/platform"}},
+			{Owner: "erin@example.com", Via: []string{"@org/platform"}},
+		}
+		if members := co.FindMembers("main.go", r); !reflect.DeepEqual(members, expected) {
+			t.Errorf("%s : expected %v got %v", file, expected, members)
+		}
+	}
+}
+
+func TestRosterErrors(t *testing.T) {
+	testcases := []struct {
+		teams    map[string]*RosterTeam
+		expected string
+	}{
+		{
+			teams: map[string]*RosterTeam{
+				"@org/a": {Teams: []string{"@org/b"}},
+				"@org/b": {Teams: []string{"@org/c"}},
+				"@org/c": {Teams: []string{"@org/A"}},
+			},
+			expected: "teams contain each other: @org/a -> @org/b -> @org/c -> @org/a",
+		},
+		{
+			teams:    map[string]*RosterTeam{"@org/a": {Teams: []string{"@org/a"}}},
+			expected: "teams contain each other: @org/a -> @org/a",
+		},
+		{
+			teams: map[string]*RosterTeam{
+				"@org/a": {Members: []string{"@alice", "@org/b"}},
+				"@org/b": {Teams: []string{"@org/a"}},
+			},
+			expected: "teams contain each other: @org/a -> @org/b -> @org/a",
+		},
+		{
+			teams:    map[string]*RosterTeam{"@org/a": {Teams: []string{"@org/missing"}}},
+			expected: "@org/a has an unknown child team @org/missing",
+		},
+	}
+
+	for _, tc := range testcases {
+		if _, err := NewRoster(tc.teams); err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("expected %q got %v", tc.expected, err)
+		}
+	}
+}
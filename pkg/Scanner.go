@@ -4,119 +4,189 @@ import (
 	"bufio"
 	"bytes"
 	"io"
+	"unicode/utf8"
 )
 
+// Position is a location in a CODEOWNERS file
+type Position struct {
+	Offset int // byte offset, starting at 0
+	Line   int // line number, starting at 1
+	Column int // column number counted in characters, starting at 1
+}
+
 // Scanner represents a lexical scanner.
 type Scanner struct {
-	r *bufio.Reader
+	r      *bufio.Reader
+	pos    Position // position of the next rune
+	tokPos Position // position of the last scanned token
+	bom    bool
+	err    error // first read error, the input is over once the bytes read before it are consumed
 }
 
 // NewScanner returns a new instance of Scanner.
 func NewScanner(r io.Reader) *Scanner {
-	return &Scanner{r: bufio.NewReader(r)}
+	return &Scanner{
+		r:   bufio.NewReader(r),
+		pos: Position{Line: 1, Column: 1},
+	}
 }
 
 // Scan returns the next token and literal value.
+//
+// A CODEOWNERS line is made of IDENT words separated by WS. Words may contain
+// any character and use a backslash to escape whitespace or a #. A # at the
+// start of a word begins a COMMENT running to the end of the line. Lines end
+// with an EOL whose literal is either "\n" or "\r\n".
 func (s *Scanner) Scan() (tok Token, lit string) {
-	// Read the next rune.
-	ch := s.read()
-
-	// If we see whitespace then consume all contiguous whitespace.
-	// If we see a letter then consume as an ident or reserved word.
-	// If we see a digit then consume as a number.
-	if isWhitespace(ch) {
-		s.unread()
-		return s.scanWhitespace()
-	} else if isLetter(ch) {
-		s.unread()
-		return s.scanIdent()
+	// A byte order mark is only meaningful at the very start of the input
+	if s.pos.Offset == 0 {
+		if next := s.peek(len(bom)); string(next) == bom {
+			_, _ = s.r.Discard(len(bom))
+			s.pos.Offset += len(bom)
+			s.bom = true
+		}
 	}
 
-	// Otherwise read the individual character.
-	switch ch {
-	case eof:
+	s.tokPos = s.pos
+
+	switch {
+	case s.atEOF():
 		return EOF, ""
-	case '*':
-		return ASTERISK, string(ch)
-	case '#':
-		return HASH, string(ch)
-	case ',':
-		return COMMA, string(ch)
-	case newLine:
-		return EOL, ""
+	case s.atLineEnd():
+		var buf bytes.Buffer
+		for ch := s.readTo(&buf); ch != newLine && ch != eof; ch = s.readTo(&buf) {
+		}
+		return EOL, buf.String()
+	case s.atWhitespace():
+		return s.scanWhitespace()
+	case s.peekByte() == '#':
+		return s.scanComment()
 	}
 
-	return ILLEGAL, string(ch)
+	return s.scanIdent()
+}
+
+// Position returns the position of the last scanned token
+func (s *Scanner) Position() Position {
+	return s.tokPos
+}
+
+// Err returns the first error met while reading the input, nil when the
+// whole input was read
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// HasBOM reports whether the input started with a UTF-8 byte order mark
+func (s *Scanner) HasBOM() bool {
+	return s.bom
 }
 
-// scanWhitespace consumes the current rune and all contiguous whitespace.
+// scanWhitespace consumes all contiguous whitespace.
 func (s *Scanner) scanWhitespace() (tok Token, lit string) {
-	// Create a buffer and read the current character into it.
 	var buf bytes.Buffer
-	buf.WriteRune(s.read())
-
-	// Read every subsequent whitespace character into the buffer.
-	// Non-whitespace characters and EOF will cause the loop to exit.
-	for {
-		if ch := s.read(); ch == eof {
-			break
-		} else if !isWhitespace(ch) {
-			s.unread()
-			break
-		} else {
-			buf.WriteRune(ch)
-		}
+	for s.atWhitespace() {
+		s.readTo(&buf)
 	}
-
 	return WS, buf.String()
 }
 
-// scanIdent consumes the current rune and all contiguous ident runes.
-func (s *Scanner) scanIdent() (tok Token, lit string) {
-	// Create a buffer and read the current character into it.
+// scanComment consumes a # and everything up to the end of the line.
+func (s *Scanner) scanComment() (tok Token, lit string) {
 	var buf bytes.Buffer
-	buf.WriteRune(s.read())
-
-	// Read every subsequent ident character into the buffer.
-	// Non-ident characters and EOF will cause the loop to exit.
-	for {
-		if ch := s.read(); ch == eof {
-			break
-		} else if !isLetter(ch) && !isDigit(ch) && ch != '_' {
-			s.unread()
-			break
-		} else {
-			_, _ = buf.WriteRune(ch)
-		}
+	for !s.atEOF() && !s.atLineEnd() {
+		s.readTo(&buf)
 	}
+	return COMMENT, buf.String()
+}
 
-	// Otherwise return as a regular identifier.
+// scanIdent consumes all runes up to the next unescaped whitespace or line
+// ending. Escape sequences are kept in the literal.
+func (s *Scanner) scanIdent() (tok Token, lit string) {
+	var buf bytes.Buffer
+	escaped := false
+	for !s.atEOF() && !s.atLineEnd() && (escaped || !s.atWhitespace()) {
+		ch := s.readTo(&buf)
+		escaped = !escaped && ch == '\\'
+	}
 	return IDENT, buf.String()
 }
 
-// read reads the next rune from the buffered reader.
-// Returns the rune(0) if an error occurs (or io.EOF is returned).
-func (s *Scanner) read() rune {
-	ch, _, err := s.r.ReadRune()
-	if err != nil {
+// readTo reads the next rune and writes it to buf exactly as it appears in the
+// input, so invalid UTF-8 survives a round trip.
+// Returns eof at the end of the input.
+func (s *Scanner) readTo(buf *bytes.Buffer) rune {
+	next := s.peek(utf8.UTFMax)
+	if len(next) == 0 {
 		return eof
 	}
+	ch, size := utf8.DecodeRune(next)
+	buf.Write(next[:size])
+	_, _ = s.r.Discard(size)
+
+	s.pos.Offset += size
+	if ch == newLine {
+		s.pos.Line++
+		s.pos.Column = 1
+	} else {
+		s.pos.Column++
+	}
 	return ch
 }
 
-// unread places the previously read rune back on the reader.
-func (s *Scanner) unread() { _ = s.r.UnreadRune() }
+// peekByte returns the next byte without consuming it, 0 at the end of the input.
+func (s *Scanner) peekByte() byte {
+	next := s.peek(1)
+	if len(next) == 0 {
+		return 0
+	}
+	return next[0]
+}
 
-// isWhitespace returns true if the rune is a space, tab, or newline.
-func isWhitespace(ch rune) bool { return ch == ' ' || ch == '\t' || ch == '\n' }
+// peek returns up to the next n bytes without consuming them. A read error
+// other than the end of the input is kept and reported by Err, the bytes read
+// before it are still returned.
+func (s *Scanner) peek(n int) []byte {
+	if s.err != nil {
+		// Nothing is read after an error, only the buffered bytes are left
+		if n > s.r.Buffered() {
+			n = s.r.Buffered()
+		}
+		next, _ := s.r.Peek(n)
+		return next
+	}
+	next, err := s.r.Peek(n)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		s.err = err
+	}
+	return next
+}
+
+func (s *Scanner) atEOF() bool {
+	return len(s.peek(1)) == 0
+}
 
-// isLetter returns true if the rune is a letter.
-func isLetter(ch rune) bool { return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') }
+// atLineEnd reports whether the next runes are "\n" or "\r\n"
+func (s *Scanner) atLineEnd() bool {
+	next := s.peek(2)
+	return (len(next) > 0 && next[0] == '\n') || string(next) == "\r\n"
+}
+
+// atWhitespace reports whether the next rune is whitespace, a carriage return
+// not followed by a new line counts as whitespace
+func (s *Scanner) atWhitespace() bool {
+	ch := s.peekByte()
+	return isWhitespace(rune(ch)) || (ch == '\r' && !s.atLineEnd())
+}
 
-// isDigit returns true if the rune is a digit.
-func isDigit(ch rune) bool { return (ch >= '0' && ch <= '9') }
+// isWhitespace returns true if the rune is a space or tab, line endings are
+// tokens of their own.
+func isWhitespace(ch rune) bool { return ch == ' ' || ch == '\t' || ch == '\f' || ch == '\v' }
 
 // eof represents a marker rune for the end of the reader.
-var eof = rune(0)
+var eof = rune(-1)
 
 var newLine = rune('\n')
+
+// bom is the UTF-8 byte order mark some editors write at the start of a file
+const bom = "\xef\xbb\xbf"
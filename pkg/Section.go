@@ -0,0 +1,85 @@
+package codeowners
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Section is a GitLab CODEOWNERS section. Rules below a section header belong
+// to it until the next header, e.g
+//
+//	[Documentation] @docs-team
+//	^[Optional Section]
+//	[Database][2] @dba
+//
+// GitLab applies the last matching rule of every section and combines the
+// owners of all sections. Sections sharing a name, regardless of case, are
+// treated as one section.
+type Section struct {
+	Name          string
+	Optional      bool     // header starts with ^, approval from the section is not required
+	Approvals     int      // approvals required from the section, 0 when the header does not set one
+	DefaultOwners []string // owners of the rules in the section which do not list their own
+	comment       string   // inline comment of the header including its leading #
+	file          string
+	line          int
+}
+
+var sectionHeader = regexp.MustCompile(`^(\^?)\[([^\]]+)\](?:\[(\d+)\])?`)
+
+// parseSectionHeader returns the section declared at the start of the
+// content along with the length of the header, nil when the content is not a
+// section header. "[Mm]akefile" is a path and not a section as the header
+// must be followed by whitespace or the end of the line.
+func parseSectionHeader(content string) (*Section, int) {
+	m := sectionHeader.FindStringSubmatch(content)
+	if m == nil {
+		return nil, 0
+	}
+	if rest := content[len(m[0]):]; rest != "" && !isWhitespace(rune(rest[0])) {
+		return nil, 0
+	}
+
+	s := &Section{
+		Name:          strings.TrimSpace(m[2]),
+		Optional:      m[1] == "^",
+		DefaultOwners: []string{},
+	}
+	if m[3] != "" {
+		s.Approvals, _ = strconv.Atoi(m[3])
+	}
+	return s, len(m[0])
+}
+
+// Line returns the line number of the section header
+func (s *Section) Line() int {
+	return s.line
+}
+
+// File returns the file the section header was parsed from when the index
+// was compiled from fragments, empty otherwise
+func (s *Section) File() string {
+	return s.file
+}
+
+// key identifies the section when combining sections which share a name
+func (s *Section) key() string {
+	if s == nil {
+		return ""
+	}
+	return strings.ToLower(s.Name)
+}
+
+// String formats the section header as a CODEOWNERS line
+func (s *Section) String() string {
+	header := fmt.Sprintf("[%s]", s.Name)
+	if s.Optional {
+		header = "^" + header
+	}
+	if s.Approvals > 0 {
+		header += fmt.Sprintf("[%d]", s.Approvals)
+	}
+	return strings.Join(append([]string{header}, s.DefaultOwners...), " ")
+}
@@ -15,4 +15,7 @@ const (
 	ASTERISK // *
 	COMMA    // ,
 	HASH     // #
+
+	// Comments, after the other tokens so their values do not change
+	COMMENT // # a comment
 )
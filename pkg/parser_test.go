@@ -1,6 +1,10 @@
 package codeowners
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
 	"reflect"
 	"strings"
 	"testing"
@@ -68,6 +72,15 @@ func TestSimpleParser(t *testing.T) {
 				owners: []string{"@product", "alecharmon@outlook.com"},
 			},
 		},
+		{
+			input: "/app/lib/ @product",
+			output: &Entry{
+				path:     "app/lib/",
+				anchored: true,
+				suffix:   PathSufix(Recursive),
+				owners:   []string{"@product"},
+			},
+		},
 		{
 			input: "app/lib/ @product alecharmon@outlook.com #some comment",
 			output: &Entry{
@@ -77,6 +90,64 @@ func TestSimpleParser(t *testing.T) {
 				owners:  []string{"@product", "alecharmon@outlook.com"},
 			},
 		},
+		{
+			input: "path\\ with\\ spaces/ @space-owner #spaces",
+			output: &Entry{
+				path:    "path\\ with\\ spaces/",
+				suffix:  PathSufix(Recursive),
+				comment: "#spaces",
+				owners:  []string{"@space-owner"},
+			},
+		},
+		{
+			input: "\\#file_with_pound.rb\t@pound  @hash",
+			output: &Entry{
+				path:   "\\#file_with_pound.rb",
+				suffix: PathSufix(Absolute),
+				owners: []string{"@pound", "@hash"},
+			},
+		},
+		{
+			input: "app/\\*.rb @literal",
+			output: &Entry{
+				path:   "app/\\*.rb",
+				suffix: PathSufix(Absolute),
+				owners: []string{"@literal"},
+			},
+		},
+		{
+			input: "docs/generated/ #no owners",
+			output: &Entry{
+				path:    "docs/generated/",
+				suffix:  PathSufix(Recursive),
+				comment: "#no owners",
+				owners:  make([]string, 0),
+			},
+		},
+		{
+			input: "docs/** @docs",
+			output: &Entry{
+				path:   "docs/**",
+				suffix: PathSufix(Recursive),
+				owners: []string{"@docs"},
+			},
+		},
+		{
+			input: "docs/**/*.md @docs",
+			output: &Entry{
+				path:   "docs/**/*.md",
+				suffix: PathSufix(Type),
+				owners: []string{"@docs"},
+			},
+		},
+		{
+			input: "app/*/ @app-modules",
+			output: &Entry{
+				path:   "app/*/",
+				suffix: PathSufix(Recursive),
+				owners: []string{"@app-modules"},
+			},
+		},
 	}
 	for _, tc := range testcases {
 		p := NewParser(strings.NewReader(tc.input))
@@ -90,3 +161,217 @@ func TestSimpleParser(t *testing.T) {
 		}
 	}
 }
+
+func TestParseFile(t *testing.T) {
+	input := "\xef\xbb\xbf# owners\r\n\r\n*.js\t@frontend\t# web\r\n  docs/ü/  @docs \r\n/\\#tag\\ file.rb @a\n# trailing"
+	entries, errors := NewParser(strings.NewReader(input)).ParseFile()
+	if len(errors) != 0 {
+		t.Fatalf("expected no errors got %v", errors)
+	}
+
+	expected := []*Entry{
+		{comment: "# owners", suffix: PathSufix(None), owners: []string{}, line: 1},
+		{path: "*.js", comment: "# web", suffix: PathSufix(Type), owners: []string{"@frontend"}, line: 3},
+		{path: "docs/ü/", suffix: PathSufix(Recursive), owners: []string{"@docs"}, line: 4},
+		{path: "\\#tag\\ file.rb", anchored: true, suffix: PathSufix(Absolute), owners: []string{"@a"}, line: 5},
+		{comment: "# trailing", suffix: PathSufix(None), owners: []string{}, line: 6},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries got %d", len(expected), len(entries))
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(entries[i], expected[i]) {
+			t.Errorf("Expected, \n %#v \n got \n %#v", expected[i], entries[i])
+		}
+	}
+}
+
+func TestScannerPositions(t *testing.T) {
+	s := NewScanner(strings.NewReader("\xef\xbb\xbfü @a\r\nb"))
+	expected := []struct {
+		tok Token
+		lit string
+		pos Position
+	}{
+		{tok: IDENT, lit: "ü", pos: Position{Offset: 3, Line: 1, Column: 1}},
+		{tok: WS, lit: " ", pos: Position{Offset: 5, Line: 1, Column: 2}},
+		{tok: IDENT, lit: "@a", pos: Position{Offset: 6, Line: 1, Column: 3}},
+		{tok: EOL, lit: "\r\n", pos: Position{Offset: 8, Line: 1, Column: 5}},
+		{tok: IDENT, lit: "b", pos: Position{Offset: 10, Line: 2, Column: 1}},
+		{tok: EOF, lit: "", pos: Position{Offset: 11, Line: 2, Column: 2}},
+	}
+	for _, e := range expected {
+		tok, lit := s.Scan()
+		if tok != e.tok || lit != e.lit || s.Position() != e.pos {
+			t.Errorf("expected %v %q at %+v got %v %q at %+v", e.tok, e.lit, e.pos, tok, lit, s.Position())
+		}
+	}
+	if !s.HasBOM() {
+		t.Errorf("expected the byte order mark to be detected")
+	}
+}
+
+func TestParseFileOddInput(t *testing.T) {
+	inputs := []string{
+		"",
+		"\n\n\n",
+		"/",
+		"/ @a",
+		"\\",
+		"a\\",
+		"#",
+		"\r",
+		"\r\r\n",
+		"\x00 @a",
+		"\xff\xfe @a",
+		"@a @b",
+		"a\\\n@b",
+		strings.Repeat("a", 100000) + " @a",
+	}
+	r := rand.New(rand.NewSource(1))
+	alphabet := []byte("ab/*?[]\\# \t\r\n@.\xef\xbb\xbf\xff")
+	for i := 0; i < 500; i++ {
+		b := make([]byte, r.Intn(40))
+		for j := range b {
+			b[j] = alphabet[r.Intn(len(alphabet))]
+		}
+		inputs = append(inputs, string(b))
+	}
+
+	for _, input := range inputs {
+		doc, _ := NewParser(strings.NewReader(input)).ParseDocument()
+		if doc.String() != input {
+			t.Errorf("expected a byte identical document for %q got %q", input, doc.String())
+		}
+		co, _ := createIndexFromDocument(doc)
+		co.FindOwners("a/b/c")
+		co.FindDirectoryOwners("a")
+	}
+}
+
+func TestParseSectionHeaders(t *testing.T) {
+	testcases := []struct {
+		input   string
+		section *Section
+	}{
+		{input: "[Docs]", section: &Section{Name: "Docs", DefaultOwners: []string{}}},
+		{input: "  [Section with spaces] @a @b # comment", section: &Section{Name: "Section with spaces", DefaultOwners: []string{"@a", "@b"}, comment: "# comment"}},
+		{input: "^[Optional]", section: &Section{Name: "Optional", Optional: true, DefaultOwners: []string{}}},
+		{input: "[Approvals][3] @a", section: &Section{Name: "Approvals", Approvals: 3, DefaultOwners: []string{"@a"}}},
+		{input: "[Mm]akefile @a", section: nil},
+		{input: "[] @a", section: nil},
+	}
+	for _, tc := range testcases {
+		doc, errors := NewParser(strings.NewReader(tc.input)).ParseDocument()
+		if len(errors) != 0 {
+			t.Errorf("%s : expected no errors got %v", tc.input, errors)
+			continue
+		}
+		var section *Section
+		if sections := doc.Sections(); len(sections) > 0 {
+			section = sections[0]
+			section.line = 0
+		}
+		if !reflect.DeepEqual(section, tc.section) {
+			t.Errorf("%s : expected %#v got %#v", tc.input, tc.section, section)
+		}
+	}
+}
+
+// failingReader returns its content and then fails instead of reaching the end
+type failingReader struct {
+	r   io.Reader
+	err error
+}
+
+func (f *failingReader) Read(b []byte) (int, error) {
+	n, err := f.r.Read(b)
+	if err == io.EOF {
+		return n, f.err
+	}
+	return n, err
+}
+
+func TestParseReader(t *testing.T) {
+	// Lines much longer than any read buffer are parsed as a single entry
+	longPath := strings.Repeat("very/long/path/", 10000) + "file.go"
+	owners := []string{}
+	for i := 0; i < 5000; i++ {
+		owners = append(owners, fmt.Sprintf("@owner-%d", i))
+	}
+	input := "# header\n\n" + longPath + " " + strings.Join(owners, " ") + "\nbad-owner nobody\n*.md @docs"
+
+	entries := []*Entry{}
+	errs := ParseReader(strings.NewReader(input), func(e *Entry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if len(errs) != 1 || errs[0].(*Diagnostic).Line != 4 {
+		t.Errorf("expected a diagnostic for line 4 got %v", errs)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries got %d", len(entries))
+	}
+	if entries[1].path != longPath || !reflect.DeepEqual(entries[1].owners, owners) || entries[1].Line() != 3 {
+		t.Errorf("the long line was not parsed as one entry")
+	}
+	if entries[2].Pattern() != "*.md" || entries[2].Line() != 5 {
+		t.Errorf("unexpected last entry %+v", entries[2])
+	}
+
+	// Errors from the callback stop parsing
+	stop := errors.New("stop")
+	calls := 0
+	errs = ParseReader(strings.NewReader(input), func(e *Entry) error {
+		calls++
+		return stop
+	})
+	if calls != 1 || len(errs) != 1 || errs[0] != stop {
+		t.Errorf("expected parsing to stop after the first entry, got %d calls and %v", calls, errs)
+	}
+}
+
+func TestParseReaderReadError(t *testing.T) {
+	readErr := errors.New("connection reset")
+	r := &failingReader{r: strings.NewReader("* @default\ndocs/ @do"), err: readErr}
+
+	entries := []*Entry{}
+	errs := ParseReader(r, func(e *Entry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if len(errs) != 1 || errs[0] != readErr {
+		t.Errorf("expected the read error got %v", errs)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected the partial line to be dropped got %v", entries)
+	}
+
+	r = &failingReader{r: strings.NewReader("* @default\n"), err: readErr}
+	if co, errs := BuildFromReader(r); co != nil || len(errs) != 1 || errs[0] != readErr {
+		t.Errorf("expected the index to fail with the read error got %v", errs)
+	}
+
+	// The bytes read before the error are scanned, the cut line is dropped
+	// without reporting it
+	inputs := []string{"* @default\n", "* @default\nab", "* @default\n@", "* @default\na @b\r", "* @default\ndocs/ @do\r\n"}
+	for _, input := range inputs {
+		entries = []*Entry{}
+		errs = ParseReader(&failingReader{r: strings.NewReader(input), err: readErr}, func(e *Entry) error {
+			entries = append(entries, e)
+			return nil
+		})
+		expected := 1
+		if strings.HasSuffix(input, "\r\n") {
+			expected = 2
+		}
+		if len(errs) != 1 || errs[0] != readErr || len(entries) != expected {
+			t.Errorf("%q : expected %d entries and the read error got %v %v", input, expected, entries, errs)
+		}
+
+		co, errs := BuildFromReader(&failingReader{r: strings.NewReader(input), err: readErr})
+		if co != nil || len(errs) != 1 || errs[0] != readErr {
+			t.Errorf("%q : expected the index to fail with the read error got %v", input, errs)
+		}
+	}
+}